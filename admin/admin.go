@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/handlers"
+)
+
+// maxBodyBytes bounds the JSON body of an admin request.
+const maxBodyBytes = 1 << 20
+
+// Server answers the admin API of the sequencer. A request is allowed when it comes with
+// a client certificate verified against the client CA, or with the bearer token.
+type Server struct {
+	control *handlers.Controller
+	token   []byte
+}
+
+// NewServer returns a Server acting on control. An empty token only allows requests with
+// a verified client certificate.
+func NewServer(control *handlers.Controller, token string) *Server {
+	return &Server{control: control, token: []byte(token)}
+}
+
+// RetryRequest is the body of POST /admin/batches/{n}/retry.
+type RetryRequest struct {
+	Stage string `json:"stage"`
+}
+
+// FailRequest is the body of POST /admin/batches/{n}/fail.
+type FailRequest struct {
+	Reason  string   `json:"reason"`
+	SkipTxs []string `json:"skip_txs"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	operator, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sequencer-admin"`)
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	logger := logs.Log.With("operator", operator, "remote", r.RemoteAddr)
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin"), "/")
+	if path == "status" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "use GET"})
+			return
+		}
+		status, err := s.control.Status()
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "use POST"})
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	switch path {
+	case "ingestion/pause":
+		s.control.PauseIngestion()
+		logger.Warn("Admin paused ingestion")
+		writeJSON(w, http.StatusOK, map[string]bool{"ingestion_paused": true})
+		return
+	case "ingestion/resume":
+		s.control.ResumeIngestion()
+		logger.Warn("Admin resumed ingestion")
+		writeJSON(w, http.StatusOK, map[string]bool{"ingestion_paused": false})
+		return
+	case "batches/seal":
+		batchNumber, txs, err := s.control.ForceSeal()
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Admin force-seal refused : %s", err.Error()))
+			writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+			return
+		}
+		logger.Warn(fmt.Sprintf("Admin force-sealed batch %d with %d transactions", batchNumber, txs), "batch", batchNumber)
+		writeJSON(w, http.StatusAccepted, map[string]int{"batch": batchNumber, "transactions": txs})
+		return
+	}
+
+	// batches/{n}/retry and batches/{n}/fail
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[0] != "batches" {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+		return
+	}
+	batchNumber, err := strconv.Atoi(parts[1])
+	if err != nil || batchNumber < 1 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid batch number %q", parts[1])})
+		return
+	}
+	logger = logger.With("batch", batchNumber)
+
+	switch parts[2] {
+	case "retry":
+		var req RetryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid body : %s", err.Error())})
+			return
+		}
+		if req.Stage != handlers.RetryProve && req.Stage != handlers.RetryDA && req.Stage != handlers.RetrySettle {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("stage must be %s, %s or %s", handlers.RetryProve, handlers.RetryDA, handlers.RetrySettle)})
+			return
+		}
+		if err := s.control.RetryStage(batchNumber, req.Stage); err != nil {
+			logger.Warn(fmt.Sprintf("Admin retry of stage %s of batch %d refused : %s", req.Stage, batchNumber, err.Error()))
+			writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+			return
+		}
+		logger.Warn(fmt.Sprintf("Admin retried stage %s of batch %d", req.Stage, batchNumber))
+		writeJSON(w, http.StatusOK, map[string]any{"batch": batchNumber, "stage": req.Stage})
+	case "fail":
+		var req FailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid body : %s", err.Error())})
+			return
+		}
+		if req.Reason == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "reason is required"})
+			return
+		}
+		if err := s.control.FailBatch(batchNumber, req.Reason, req.SkipTxs); err != nil {
+			logger.Warn(fmt.Sprintf("Admin failing of batch %d refused : %s", batchNumber, err.Error()))
+			writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+			return
+		}
+		logger.Warn(fmt.Sprintf("Admin marked batch %d failed : %s", batchNumber, req.Reason), "skip_txs", req.SkipTxs)
+		writeJSON(w, http.StatusOK, map[string]any{"batch": batchNumber, "skip_txs": req.SkipTxs})
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+	}
+}
+
+// authenticate returns who sent r: the subject of its verified client certificate, or
+// "token" for the bearer token.
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.String(), true
+	}
+	if len(s.token) == 0 {
+		return "", false
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), s.token) != 1 {
+		return "", false
+	}
+	return "token", true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
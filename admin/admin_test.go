@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/airchains-network/evm-sequencer-node/handlers"
+)
+
+func TestAuthentication(t *testing.T) {
+	clientCert := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "operator"}}}}}
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		tls           *tls.ConnectionState
+		wantCode      int
+	}{
+		{name: "no token", token: "secret", wantCode: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer other", wantCode: http.StatusUnauthorized},
+		{name: "token prefix", token: "secret", authorization: "Bearer secre", wantCode: http.StatusUnauthorized},
+		{name: "not bearer", token: "secret", authorization: "Basic secret", wantCode: http.StatusUnauthorized},
+		// without a configured token only client certificates are allowed
+		{name: "empty token", token: "", authorization: "Bearer ", wantCode: http.StatusUnauthorized},
+		{name: "token", token: "secret", authorization: "Bearer secret", wantCode: http.StatusOK},
+		{name: "client certificate", token: "", tls: clientCert, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			control := &handlers.Controller{}
+			server := NewServer(control, tt.token)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/ingestion/pause", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			req.TLS = tt.tls
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("no WWW-Authenticate header")
+			}
+			if paused := rec.Code == http.StatusOK; control.IngestionPaused() != paused {
+				t.Fatalf("ingestion paused %v, want %v", control.IngestionPaused(), paused)
+			}
+		})
+	}
+}
+
+func TestRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "status of a stopped pipeline", method: http.MethodGet, path: "/admin/status", wantCode: http.StatusServiceUnavailable, wantErr: "not running"},
+		{name: "status with POST", method: http.MethodPost, path: "/admin/status", wantCode: http.StatusMethodNotAllowed},
+		{name: "retry with GET", method: http.MethodGet, path: "/admin/batches/2/retry", wantCode: http.StatusMethodNotAllowed},
+		{name: "unknown stage", method: http.MethodPost, path: "/admin/batches/2/retry", body: `{"stage":"collect"}`, wantCode: http.StatusBadRequest, wantErr: "stage must be"},
+		{name: "invalid batch", method: http.MethodPost, path: "/admin/batches/0/retry", body: `{"stage":"da"}`, wantCode: http.StatusBadRequest, wantErr: "invalid batch number"},
+		{name: "invalid body", method: http.MethodPost, path: "/admin/batches/2/fail", body: `{`, wantCode: http.StatusBadRequest, wantErr: "invalid body"},
+		{name: "fail without reason", method: http.MethodPost, path: "/admin/batches/2/fail", body: `{}`, wantCode: http.StatusBadRequest, wantErr: "reason is required"},
+		{name: "retry of a stopped pipeline", method: http.MethodPost, path: "/admin/batches/2/retry", body: `{"stage":"da"}`, wantCode: http.StatusConflict, wantErr: "not running"},
+		{name: "unknown path", method: http.MethodPost, path: "/admin/batches/2/skip", body: `{}`, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&handlers.Controller{}, "secret")
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantErr) {
+				t.Fatalf("got %d %s, want %d with %q", rec.Code, rec.Body, tt.wantCode, tt.wantErr)
+			}
+		})
+	}
+}
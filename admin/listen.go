@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/handlers"
+)
+
+// TokenEnv is the environment variable holding the bearer token of the admin API.
+const TokenEnv = "ADMIN_TOKEN"
+
+// Config is where and how the admin API is served.
+type Config struct {
+	Address string
+	// TLSCert and TLSKey serve the API over TLS. With ClientCA, clients presenting a
+	// certificate issued by it are allowed without the token.
+	TLSCert  string
+	TLSKey   string
+	ClientCA string
+}
+
+// Start serves the admin API acting on control at config.Address. The API is off when
+// neither TokenEnv nor config.ClientCA is set; once it is served, control parks failed
+// batches for the operator instead of stopping the sequencer.
+func Start(config Config, control *handlers.Controller) error {
+	token := os.Getenv(TokenEnv)
+	if token == "" && config.ClientCA == "" {
+		logs.Log.Info(fmt.Sprintf("Admin API is off, set %s or a client CA to enable it", TokenEnv))
+		return nil
+	}
+
+	server := &http.Server{
+		Addr:    config.Address,
+		Handler: NewServer(control, token),
+	}
+
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return errors.New("the admin TLS certificate and key must be set together")
+	}
+	if config.TLSCert == "" {
+		if config.ClientCA != "" {
+			return errors.New("a client CA needs a TLS certificate and key")
+		}
+		logs.Log.Warn(fmt.Sprintf("Serving admin API without TLS on %s", config.Address))
+		control.ParkFailedBatches()
+		go func() {
+			err := server.ListenAndServe()
+			logs.Log.Error(fmt.Sprintf("Error in serving admin API on %s : %s", config.Address, err.Error()))
+		}()
+		return nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.ClientCA != "" {
+		caPEM, err := os.ReadFile(config.ClientCA)
+		if err != nil {
+			return fmt.Errorf("reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no certificates in client CA %s", config.ClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if token != "" {
+			// clients without a certificate use the token
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	server.TLSConfig = tlsConfig
+	control.ParkFailedBatches()
+
+	go func() {
+		err := server.ListenAndServeTLS(config.TLSCert, config.TLSKey)
+		logs.Log.Error(fmt.Sprintf("Error in serving admin API on %s : %s", config.Address, err.Error()))
+	}()
+	logs.Log.Info(fmt.Sprintf("Serving admin API over TLS on %s", config.Address))
+	return nil
+}
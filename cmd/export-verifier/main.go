@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+)
+
+// export-verifier writes the Solidity verifier contract of a BN254 station and, with
+// -batch, the ABI encoded calldata that verifies the proof of that batch on an EVM chain.
+// It reads the proof stores, so it must run while the sequencer is stopped.
+func main() {
+	outDir := flag.String("out", "solidity", "directory to write the contract and calldata to")
+	batchNumber := flag.Int("batch", 0, "batch to export the calldata of, 0 exports only the contract")
+	flag.Parse()
+
+	if common.ProverCurve != prover.CurveBN254 {
+		logs.Log.Error(fmt.Sprintf("Solidity verifiers require ProverCurve %s, the station uses %s", prover.CurveBN254, common.ProverCurve))
+		os.Exit(1)
+	}
+
+	err := os.MkdirAll(*outDir, 0755)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in creating %s : %s", *outDir, err.Error()))
+		os.Exit(1)
+	}
+
+	contractFile := filepath.Join(*outDir, "Verifier.sol")
+	file, err := os.Create(contractFile)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in creating %s : %s", contractFile, err.Error()))
+		os.Exit(1)
+	}
+	err = prover.ExportSolidityVerifier(common.ProverBackend, file)
+	file.Close()
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in exporting verifier contract : %s", err.Error()))
+		os.Exit(1)
+	}
+	logs.Log.Info(fmt.Sprintf("Verifier contract written to %s", contractFile))
+
+	if *batchNumber == 0 {
+		return
+	}
+
+	if !air.InitProofDb() || !air.InitPublicWitnessDb() {
+		logs.Log.Error("Error in opening the proof db")
+		os.Exit(1)
+	}
+
+	calldata, err := prover.BatchSolidityCalldata(*batchNumber)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in encoding calldata of batch %d : %s", *batchNumber, err.Error()))
+		os.Exit(1)
+	}
+
+	calldataJSON, err := json.MarshalIndent(calldata, "", "  ")
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in marshalling calldata : %s", err.Error()))
+		os.Exit(1)
+	}
+
+	calldataFile := filepath.Join(*outDir, fmt.Sprintf("batch_%d_calldata.json", *batchNumber))
+	err = os.WriteFile(calldataFile, calldataJSON, 0644)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in writing %s : %s", calldataFile, err.Error()))
+		os.Exit(1)
+	}
+	logs.Log.Info(fmt.Sprintf("Calldata of batch %d written to %s", *batchNumber, calldataFile))
+}
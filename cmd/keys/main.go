@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"golang.org/x/term"
+)
+
+const usage = `usage: keys <command> [flags] [args]
+
+commands:
+  add <name>                       generate a new key
+  import [-format f] <name> <file> import a geth keystore (default) or Cosmos-SDK armor file
+  export [-format f] <name>        print the key as a geth keystore (default) or Cosmos-SDK armor
+  list                             list the keys
+  show <name>                      print the address and public key of a key
+  delete [-y] <name>               delete a key
+
+every command accepts -dir, the keyring directory (default ` + common.KeyringDirectory + `).
+The passphrase of the keyring is read from ` + keyring.PassphraseEnv + ` or prompted for.
+`
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// keys manages the operator keys in KeyringDirectory used to sign settlement requests.
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	dir := flags.String("dir", common.KeyringDirectory, "keyring directory")
+	format := flags.String("format", "keystore", "key file format, keystore or armor")
+	yes := flags.Bool("y", false, "delete without confirmation")
+	_ = flags.Parse(os.Args[2:])
+	args := flags.Args()
+
+	var err error
+	switch command {
+	case "add":
+		err = add(*dir, args)
+	case "import":
+		err = importKey(*dir, *format, args)
+	case "export":
+		err = exportKey(*dir, *format, args)
+	case "list":
+		err = list(*dir)
+	case "show":
+		err = show(*dir, args)
+	case "delete":
+		err = deleteKey(*dir, *yes, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func add(dir string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("add takes the key name")
+	}
+
+	passphrase, err := newPassphrase()
+	if err != nil {
+		return err
+	}
+	key, err := keyring.Create(dir, args[0], passphrase)
+	if err != nil {
+		return err
+	}
+
+	printKey(key)
+	return nil
+}
+
+func importKey(dir string, format string, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("import takes the key name and the file to import")
+	}
+
+	contents, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	filePassphrase, err := promptPassphrase("Passphrase of the imported key: ")
+	if err != nil {
+		return err
+	}
+	passphrase, err := newPassphrase()
+	if err != nil {
+		return err
+	}
+
+	var key *keyring.Key
+	switch format {
+	case "keystore":
+		key, err = keyring.ImportKeystore(dir, args[0], contents, filePassphrase, passphrase)
+	case "armor":
+		key, err = keyring.ImportArmor(dir, args[0], string(contents), filePassphrase, passphrase)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	printKey(key)
+	return nil
+}
+
+func exportKey(dir string, format string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("export takes the key name")
+	}
+
+	key, err := load(dir, args[0])
+	if err != nil {
+		return err
+	}
+	exportPassphrase, err := promptPassphrase("Passphrase of the exported key: ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "keystore":
+		keyJSON, err := key.ExportKeystore(exportPassphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(keyJSON))
+	case "armor":
+		armored, err := key.ExportArmor(exportPassphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Println(armored)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	return nil
+}
+
+func list(dir string) error {
+	names, err := keyring.List(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func show(dir string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("show takes the key name")
+	}
+
+	key, err := load(dir, args[0])
+	if err != nil {
+		return err
+	}
+
+	printKey(key)
+	return nil
+}
+
+func deleteKey(dir string, yes bool, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("delete takes the key name")
+	}
+	if _, err := os.Stat(keyring.KeyFile(dir, args[0])); err != nil {
+		return err
+	}
+
+	if !yes {
+		answer, err := prompt(fmt.Sprintf("Delete key %s? It cannot be recovered without a backup [y/N]: ", args[0]))
+		if err != nil {
+			return err
+		}
+		if strings.ToLower(answer) != "y" {
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	return keyring.Delete(dir, args[0])
+}
+
+func load(dir string, name string) (*keyring.Key, error) {
+	passphrase, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Load(dir, name, passphrase)
+}
+
+func printKey(key *keyring.Key) {
+	fmt.Printf("name: %s\naddress: %s\npubkey: %s\n", key.Name, key.Address(), hex.EncodeToString(key.PubKey()))
+}
+
+// passphrase returns the keyring passphrase from the environment or the terminal.
+func passphrase() (string, error) {
+	if passphrase := os.Getenv(keyring.PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	return promptPassphrase("Keyring passphrase: ")
+}
+
+// newPassphrase is passphrase, asked twice when it is typed in.
+func newPassphrase() (string, error) {
+	if passphrase := os.Getenv(keyring.PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	passphrase, err := promptPassphrase("Keyring passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	repeated, err := promptPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != repeated {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+func prompt(message string) (string, error) {
+	fmt.Fprint(os.Stderr, message)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptPassphrase is prompt without echoing the answer when stdin is a terminal.
+func promptPassphrase(message string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(message)
+	}
+
+	fmt.Fprint(os.Stderr, message)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/handlers/settlement-client/mock"
+)
+
+// mock-settlement serves an in-memory settlement layer on the address of
+// SettlementClientRPC, for running the sequencer against a local devnet.
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8080", "address the mock settlement layer listens on")
+	flag.Parse()
+
+	logs.Log.Info(fmt.Sprintf("Mock settlement layer listening on %s", *listen))
+	if err := http.ListenAndServe(*listen, mock.NewServer()); err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in serving mock settlement layer : %s", err.Error()))
+		os.Exit(1)
+	}
+}
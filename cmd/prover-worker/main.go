@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
+	"google.golang.org/grpc"
+)
+
+// prover-worker serves batch proving jobs over gRPC for sequencers configured with
+// ProverWorkerRPC. It needs the same proving key as the sequencer. Jobs are served over TLS
+// with -tls-cert and -tls-key, and only accepted with the PROVER_WORKER_TOKEN token when
+// it is set.
+func main() {
+	listenAddr := flag.String("listen", "0.0.0.0:9090", "address to serve proving jobs on")
+	provingKeyFile := flag.String("proving-key", prover.ProvingKeyFile(common.ProverBackend, common.ProverCurve), "path to the proving key")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate to serve proving jobs with")
+	tlsKey := flag.String("tls-key", "", "TLS key of -tls-cert")
+	flag.Parse()
+
+	logs.Log.Info("Starting prover worker")
+
+	ctx := context.Background()
+	err := tracing.Init(ctx, tracing.Config{
+		ServiceName: "prover-worker",
+		Exporter:    common.TraceExporter,
+		Endpoint:    common.TraceEndpoint,
+		Insecure:    common.TraceInsecure,
+		File:        common.TraceFile,
+		SampleRatio: common.TraceSampleRatio,
+	})
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in initializing tracing : %s", err.Error()))
+		os.Exit(1)
+	}
+	defer tracing.Shutdown(ctx)
+
+	localProver, err := prover.NewLocalProver(common.ProverBackend, common.ProverCurve, *provingKeyFile, common.ProvingKeyMmap)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in loading prover : %s", err.Error()))
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in listening on %s : %s", *listenAddr, err.Error()))
+		os.Exit(1)
+	}
+
+	token := os.Getenv(prover.WorkerTokenEnv)
+	if token == "" {
+		logs.Log.Warn(fmt.Sprintf("%s is not set, any client reaching %s can submit proving jobs", prover.WorkerTokenEnv, *listenAddr))
+	}
+	if *tlsCert == "" {
+		logs.Log.Warn("Serving proving jobs without TLS, witnesses and tokens are sent in plaintext")
+	}
+	opts, err := prover.WorkerServerOptions(*tlsCert, *tlsKey, token)
+	if err != nil {
+		logs.Log.Error(err.Error())
+		os.Exit(1)
+	}
+
+	server := grpc.NewServer(opts...)
+	prover.RegisterWorkerServer(server, prover.NewWorkerServer(localProver))
+
+	logs.Log.Info(fmt.Sprintf("Prover worker listening on %s", *listenAddr))
+	if err := server.Serve(listener); err != nil {
+		logs.Log.Error(fmt.Sprintf("Prover worker stopped : %s", err.Error()))
+		os.Exit(1)
+	}
+}
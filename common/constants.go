@@ -1,10 +1,139 @@
 package common
 
+// BatchSize is the number of transactions the circuit proves; partial batches are padded.
 const BatchSize = 25
 const BlockDelay = 5
 const ExecutionClientRPC = "http://127.0.0.1:8545/"
 const SettlementClientRPC = "http://127.0.0.1:8080"
+
+// SettlementRetries is how many times a pod is sent to the settlement layer, waiting
+// SettlementRetryDelay seconds between attempts, before the batch fails.
+const SettlementRetries = 3
+const SettlementRetryDelay = 5
 const KeyringDirectory = "./account/keys"
 
-// var DaClientRPC = os.Getenv("DA_CLIENT_RPC")
+// NodeHTTPAddress is where the sequencer serves its Prometheus metrics on /metrics and
+// its health on /healthz and /readyz.
+const NodeHTTPAddress = "127.0.0.1:9100"
+
+// Health thresholds. /readyz fails when ingestion is more than HealthMaxBlockLag blocks
+// behind the chain head, or when transactions have waited HealthMaxBatchAge minutes without
+// a batch being verified. Each check is given HealthCheckTimeout seconds.
+const HealthMaxBlockLag = 50
+const HealthMaxBatchAge = 30
+const HealthCheckTimeout = 5
+
+// SignSettlementRequests signs every settlement request and DA upload with the operator key
+// named by the key field of config/chainInfo.json, decrypted with the KEYRING_PASSPHRASE
+// variable. Without a loadable key they are sent unsigned.
+const SignSettlementRequests = true
+
+// ProvingKeyMmap memory-maps the proving key and decodes it without subgroup checks. Only
+// enable it when nobody else can write the key file.
+const ProvingKeyMmap = false
+
+// ProverBackend selects the proving system, "groth16" or "plonk". PLONK uses the
+// universal KZG SRS in KzgSrsFile instead of a per-circuit trusted setup.
+const ProverBackend = "groth16"
+const KzgSrsFile = "kzgSrs.txt"
+
+// ProverCurve selects the curve of the circuit, "bls12_381" or "bn254". Only BN254 proofs
+// can be verified on an EVM chain with the contract exported by cmd/export-verifier.
+const ProverCurve = "bls12_381"
+
+// AggregationSize is the number of consecutive batch proofs recursively verified in one
+// aggregation proof and settled as a single pod range. 0 settles every batch on its own.
+const AggregationSize = 0
+
+// ProverWorkerRPC is a comma separated list of prover-worker gRPC addresses. When empty,
+// batches are proved in-process. With ProverWorkerCA the workers are reached over TLS and
+// verified against that CA.
+const ProverWorkerRPC = ""
+const ProverWorkerCA = ""
+const ProverJobTimeout = 600
+const ProverJobRetries = 3
+
+// SettlementPayloadVersion is the version of the add-pod and verify-pod payloads. Version 2
+// adds the DA layer, key and blob hash of the pod; set it to 1 for settlement layers that
+// do not accept them yet.
+const SettlementPayloadVersion = 2
+
+// DaClientRPC is the Celestia DA proxy, used when daSelected is "Celestia". DA_CLIENT_RPC
+// in .env overrides it.
 const DaClientRPC = "http://127.0.0.1:5050/celestia"
+
+// CelestiaNodeRPC is the JSON-RPC endpoint of a Celestia node, used instead of the proxy when
+// daSelected is "Celestia-Node". Blobs are posted under the version 0 namespace with the 10
+// byte hex ID CelestiaNamespaceID.
+const CelestiaNodeRPC = "http://127.0.0.1:26658"
+const CelestiaNamespaceID = "00616972636861696e73"
+
+// Endpoints of the other DA layers, used when daSelected in config/chainInfo.json is
+// "Avail", "EigenDA" or "Local".
+const AvailClientRPC = "http://127.0.0.1:7000"
+const AvailAppID = 0
+const EigenDAClientRPC = "http://127.0.0.1:3100"
+const LocalDADirectory = "data/da"
+
+// DACompression is the codec of batch blobs, "zstd", "brotli", "gzip" or "none".
+// Batch uploads whose JSON is larger than DAMaxBlobSize bytes have their blob split into
+// chunks listed in a manifest; every submission stays within DAMaxBlobSize.
+const DACompression = "zstd"
+const DAMaxBlobSize = 1 << 20
+
+// DAFailoverAfter is the number of consecutive failed submissions after which batches
+// are sent to daSecondary of config/chainInfo.json instead of daSelected.
+const DAFailoverAfter = 3
+
+// DAVerifyRetries is how many times a batch whose blob cannot be read back or does not
+// match is re-posted to DA before the batch is failed.
+const DAVerifyRetries = 3
+
+// PipelineDepth is the number of batches that may be collected ahead of the last verified
+// batch, so proving and DA posting of later batches overlap with settlement of earlier ones.
+// With AggregationSize the collector may go on to the end of the range it reaches.
+const PipelineDepth = 4
+
+// Sealing policy of batches. A batch is sealed when it holds BatchMaxTxs transactions (at
+// most BatchSize), BatchMaxWait seconds after the block of its first transaction, or when
+// the next transaction would take its input data over BatchMaxCalldata bytes or its gas
+// limits over BatchMaxGas. A limit of 0 is not applied.
+const BatchMaxTxs = BatchSize
+const BatchMaxWait = 60
+const BatchMaxCalldata = 128 * 1024
+const BatchMaxGas = 30000000
+
+// Logging. Logs from LogLevel up are written as LogFormat ("json" or "text") to LogFile,
+// which is rotated every LogMaxSizeMB megabytes, keeping LogMaxBackups files for at most
+// LogMaxAgeDays days. LogConsole also writes them to stderr.
+const LogLevel = "info"
+const LogFormat = "json"
+const LogFile = "data/logs/sequencer.log"
+const LogMaxSizeMB = 100
+const LogMaxBackups = 10
+const LogMaxAgeDays = 30
+const LogConsole = true
+
+// Tracing. TraceExporter is "otlp" to send spans to the OTLP/HTTP collector at
+// TraceEndpoint (plain HTTP with TraceInsecure), "file" to append them as JSON to
+// TraceFile, or "none". TraceSampleRatio is the fraction of batches traced.
+const TraceExporter = "none"
+const TraceEndpoint = "localhost:4318"
+const TraceInsecure = true
+const TraceFile = "data/traces/traces.json"
+const TraceSampleRatio = 1.0
+
+// Events. The last EventHistory events are kept for event stream clients and webhooks
+// that reconnect. A webhook delivery is attempted WebhookMaxAttempts times, each with a
+// timeout of WebhookTimeout seconds, before the event is skipped.
+const EventHistory = 1024
+const WebhookMaxAttempts = 8
+const WebhookTimeout = 10
+
+// Admin API. It is served on AdminHTTPAddress when the ADMIN_TOKEN variable or
+// AdminClientCA is set, over TLS with AdminTLSCert and AdminTLSKey. Clients with a
+// certificate issued by AdminClientCA need no token.
+const AdminHTTPAddress = "127.0.0.1:9101"
+const AdminTLSCert = ""
+const AdminTLSKey = ""
+const AdminClientCA = ""
@@ -4,12 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rpc"
 	"io"
 	"log"
 	"math/big"
-	"net/http"
 	"strconv"
 	"strings"
 )
@@ -34,7 +34,7 @@ func GetBalance(address string, blockNumber uint64) (string, error) {
 		"id": 1
 	}`, address, "0x"+strconv.FormatUint(blockNumber, 16))
 
-	resp, err := http.Post(ExecutionClientRPC, "application/json", strings.NewReader(payload))
+	resp, err := metrics.RPCClient.Post(ExecutionClientRPC, "application/json", strings.NewReader(payload))
 	if err != nil {
 		return "", err
 	}
@@ -70,16 +70,16 @@ func GetBalance(address string, blockNumber uint64) (string, error) {
 }
 
 func GetAccountNonce(ctx context.Context, address string, blockNumber uint64) (string, error) {
-	client, err := rpc.Dial(ExecutionClientRPC)
+	client, err := rpc.DialOptions(ctx, ExecutionClientRPC, rpc.WithHTTPClient(metrics.RPCClient))
 	if err != nil {
 		return "0", err
 	}
+	defer client.Close()
 	accountAddress := common.HexToAddress(address)
 	formatedBlockNumber := "0x" + strconv.FormatUint(blockNumber, 16)
 	var result string
 	err = client.CallContext(ctx, &result, "eth_getTransactionCount", accountAddress, formatedBlockNumber)
 	if err != nil {
-		fmt.Println("Error getting transaction count:", err)
 		return "0", err
 	}
 
@@ -1,7 +0,0 @@
-package logs
-
-import (
-	"github.com/ComputerKeeda/sslogger"
-)
-
-var Log sslogger.Logger
@@ -0,0 +1,92 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Log is the logger of the sequencer. Until Init is called it writes text to stderr.
+// Loggers for a block, transaction or batch are derived from it with With, e.g.
+// logs.Log.With("batch", 12).
+var Log = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+var output io.Writer = os.Stderr
+
+// Config selects the level, format and destination of the logs.
+type Config struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string
+	// Format is "json" or "text".
+	Format string
+	// File is rotated once it reaches MaxSizeMB; MaxBackups rotated files are kept for at
+	// most MaxAgeDays. Without a file the logs go to stderr.
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	// Console also writes the logs to stderr when File is set.
+	Console bool
+}
+
+// Init replaces Log, and the default slog logger, with a logger configured by config.
+func Init(config Config) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return fmt.Errorf("invalid log level %q", config.Level)
+	}
+
+	var writers []io.Writer
+	if config.File != "" {
+		writers = append(writers, &lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSizeMB,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAgeDays,
+		})
+	}
+	if config.File == "" || config.Console {
+		writers = append(writers, os.Stderr)
+	}
+	output = io.MultiWriter(writers...)
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch config.Format {
+	case "json":
+		handler = slog.NewJSONHandler(output, options)
+	case "text":
+		handler = slog.NewTextHandler(output, options)
+	default:
+		return fmt.Errorf("invalid log format %q", config.Format)
+	}
+
+	Log = slog.New(handler)
+	slog.SetDefault(Log)
+	return nil
+}
+
+// Output is where the logs are written, for libraries that take a writer.
+func Output() io.Writer {
+	return output
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying logger, so the fields of logger follow a batch through
+// the calls made with ctx.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or Log.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return Log
+}
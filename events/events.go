@@ -0,0 +1,180 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
+)
+
+// Types of the events of the sequencer.
+const (
+	TypeBlockIngested  = "block.ingested"
+	TypeBatchSealed    = "batch.sealed"
+	TypeBatchProved    = "batch.proved"
+	TypeBatchDAPosted  = "batch.da_posted"
+	TypeBatchSubmitted = "batch.submitted"
+	TypeBatchVerified  = "batch.verified"
+	TypeBatchFailed    = "batch.failed"
+)
+
+// Event is one step of the lifecycle of a block or batch. IDs increase, also across
+// restarts, so a consumer can ask for the events after the last one it has seen.
+type Event struct {
+	ID   uint64          `json:"id"`
+	Type string          `json:"type"`
+	Time int64           `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// BlockIngested is the data of block.ingested, sent once a block and its transactions are
+// saved.
+type BlockIngested struct {
+	Block        int    `json:"block"`
+	Hash         string `json:"hash"`
+	Transactions int    `json:"transactions"`
+}
+
+// BatchSealed is the data of batch.sealed, sent when the sealing policy closes a batch.
+type BatchSealed struct {
+	Batch      int    `json:"batch"`
+	TxStart    int    `json:"tx_start"`
+	TxEnd      int    `json:"tx_end"`
+	SealReason string `json:"seal_reason"`
+}
+
+// BatchProved is the data of batch.proved.
+type BatchProved struct {
+	Batch            int    `json:"batch"`
+	CurrentStateHash string `json:"current_state_hash"`
+}
+
+// BatchDAPosted is the data of batch.da_posted, sent each time a batch is published to
+// a DA layer.
+type BatchDAPosted struct {
+	Batch   int    `json:"batch"`
+	DALayer string `json:"da_layer"`
+	DAKey   string `json:"da_key"`
+	Chunks  int    `json:"chunks"`
+}
+
+// BatchSubmitted is the data of batch.submitted, sent when the settlement layer accepts a
+// pod.
+type BatchSubmitted struct {
+	Batch          int    `json:"batch"`
+	StationId      string `json:"station_id"`
+	MerkleRootHash string `json:"merkle_root_hash"`
+}
+
+// BatchVerified is the data of batch.verified, sent when the settlement layer verifies
+// the proof of a pod. Batches verified by an aggregation proof carry their range.
+type BatchVerified struct {
+	Batch      int    `json:"batch"`
+	StationId  string `json:"station_id"`
+	RangeFirst int    `json:"range_first,omitempty"`
+	RangeLast  int    `json:"range_last,omitempty"`
+}
+
+// BatchFailed is the data of batch.failed, sent when a stage of a batch fails or an
+// operator fails the batch.
+type BatchFailed struct {
+	Batch int    `json:"batch"`
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// Bus delivers every published event to its subscribers and keeps the last events for
+// subscribers that reconnect.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	size        int
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus returns a Bus keeping the last size events. IDs start from the current time in
+// microseconds, so they keep increasing after a restart.
+func NewBus(size int) *Bus {
+	return &Bus{
+		lastID:      uint64(time.Now().UnixMicro()),
+		size:        size,
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+// Default is the bus the sequencer publishes its events on.
+var Default = NewBus(common.EventHistory)
+
+// Publish publishes an event of eventType with data on Default.
+func Publish(eventType string, data any) {
+	Default.Publish(eventType, data)
+}
+
+// Publish sends an event of eventType with data to every subscriber. It never blocks: a
+// subscriber whose buffer is full is dropped, and can subscribe again from the last event
+// it received.
+func (b *Bus) Publish(eventType string, data any) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in marshalling %s event : %s", eventType, err.Error()))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Time: time.Now().Unix(), Data: dataJSON}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	metrics.EventsPublished.WithLabelValues(eventType).Inc()
+}
+
+// Subscribe returns a channel receiving the kept events after afterID, then every new
+// event, and a function ending the subscription. The channel is closed when the
+// subscriber falls too far behind or the subscription ends.
+func (b *Bus) Subscribe(afterID uint64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, 2*b.size)
+	for _, event := range b.history {
+		if event.ID > afterID {
+			ch <- event
+		}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// LastID returns the ID of the last published event.
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Computed with: printf '<timestamp>.<body>' | openssl dgst -sha256 -hmac "webhook secret"
+func TestSign(t *testing.T) {
+	body := `{"id":1,"type":"batch.sealed","time":1700000000,"data":{"batch":1}}`
+	want := "63719c184d96c5cf13a0632720ffda2511582e1777cf518003832e0bdcbc6ec3"
+	if got := Sign([]byte("webhook secret"), "1700000000", []byte(body)); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestWebhookRequest(t *testing.T) {
+	secret := "webhook secret"
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	bus := NewBus(8)
+	bus.Publish(TypeBatchSealed, BatchSealed{Batch: 7, TxStart: 10, TxEnd: 35, SealReason: "tx-count"})
+	ch, cancel := bus.Subscribe(0)
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go NewWebhook(server.URL, secret).consume(ctx, ch, 0)
+
+	var r *http.Request
+	var body []byte
+	select {
+	case r = <-requests:
+		body = <-bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook request")
+	}
+
+	// the signature is checked the way the README tells receivers to
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(r.Header.Get(HeaderTimestamp) + "." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(HeaderSignature) != want {
+		t.Fatalf("signature %q, want %q", r.Header.Get(HeaderSignature), want)
+	}
+	if _, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64); err != nil {
+		t.Fatalf("invalid timestamp %q", r.Header.Get(HeaderTimestamp))
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.Get(HeaderEvent) != TypeBatchSealed || r.Header.Get(HeaderEventID) != strconv.FormatUint(event.ID, 10) {
+		t.Fatalf("unexpected headers %v", r.Header)
+	}
+	if event.Type != TypeBatchSealed || string(event.Data) != `{"batch":7,"tx_start":10,"tx_end":35,"seal_reason":"tx-count"}` {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestSubscribeHistory(t *testing.T) {
+	bus := NewBus(2)
+	for batch := 1; batch <= 3; batch++ {
+		bus.Publish(TypeBatchProved, BatchProved{Batch: batch})
+	}
+	ch, cancel := bus.Subscribe(0)
+	defer cancel()
+
+	// only the last 2 events are kept
+	var got []uint64
+	for len(got) < 2 {
+		got = append(got, (<-ch).ID)
+	}
+	if want := []uint64{bus.LastID() - 1, bus.LastID()}; got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+}
+
+// readEvents reads n server-sent events from body.
+func readEvents(t *testing.T, body io.Reader, n int) []map[string]string {
+	t.Helper()
+	var events []map[string]string
+	event := map[string]string{}
+	scanner := bufio.NewScanner(body)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(event) > 0 {
+				events = append(events, event)
+				event = map[string]string{}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		event[field] = value
+	}
+	if len(events) < n {
+		t.Fatalf("read %d events, want %d: %v", len(events), n, scanner.Err())
+	}
+	return events
+}
+
+func TestHandlerReplay(t *testing.T) {
+	bus := NewBus(8)
+	bus.Publish(TypeBatchSealed, BatchSealed{Batch: 1})
+	first := bus.LastID()
+	bus.Publish(TypeBatchProved, BatchProved{Batch: 1})
+	bus.Publish(TypeBatchVerified, BatchVerified{Batch: 1})
+	server := httptest.NewServer(Handler(bus))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		header   string
+		wantIDs  []uint64
+		wantCode int
+	}{
+		{name: "Last-Event-ID", header: strconv.FormatUint(first, 10), wantIDs: []uint64{first + 1, first + 2}, wantCode: http.StatusOK},
+		{name: "last_event_id", query: "?last_event_id=" + strconv.FormatUint(first+1, 10), wantIDs: []uint64{first + 2}, wantCode: http.StatusOK},
+		{name: "types", query: "?types=batch.verified&last_event_id=0", wantIDs: []uint64{first + 2}, wantCode: http.StatusOK},
+		{name: "invalid id", header: "latest", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantCode {
+				t.Fatalf("status %d, want %d", res.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			for i, event := range readEvents(t, res.Body, len(tt.wantIDs)) {
+				var data Event
+				if err := json.Unmarshal([]byte(event["data"]), &data); err != nil {
+					t.Fatal(err)
+				}
+				if event["id"] != strconv.FormatUint(tt.wantIDs[i], 10) || data.ID != tt.wantIDs[i] || event["event"] != data.Type {
+					t.Fatalf("event %d is %v, want id %d", i, event, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
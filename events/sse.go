@@ -0,0 +1,79 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler streams the events of bus as server-sent events. A client reconnecting with the
+// Last-Event-ID header, or the last_event_id parameter, first receives the kept events it
+// missed. The types parameter, a comma separated list, selects the types sent.
+func Handler(bus *Bus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		afterID := bus.LastID()
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+		if lastEventID != "" {
+			id, err := strconv.ParseUint(lastEventID, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid last event id", http.StatusBadRequest)
+				return
+			}
+			afterID = id
+		}
+
+		types := map[string]bool{}
+		if param := r.URL.Query().Get("types"); param != "" {
+			for _, eventType := range strings.Split(param, ",") {
+				types[strings.TrimSpace(eventType)] = true
+			}
+		}
+
+		ch, cancel := bus.Subscribe(afterID)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(15 * time.Second)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case event, ok := <-ch:
+				if !ok {
+					// fell behind, the client reconnects from its last event
+					return
+				}
+				if len(types) > 0 && !types[event.Type] {
+					continue
+				}
+				eventJSON, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, eventJSON)
+			}
+			flusher.Flush()
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
+)
+
+// Environment variables configuring the webhooks.
+const (
+	// WebhookURLsEnv is a comma separated list of the URLs events are posted to.
+	WebhookURLsEnv = "WEBHOOK_URLS"
+	// WebhookSecretEnv is the key the webhook requests are signed with.
+	WebhookSecretEnv = "WEBHOOK_SECRET"
+)
+
+// Headers of a webhook request.
+const (
+	HeaderEvent     = "X-Sequencer-Event"
+	HeaderEventID   = "X-Sequencer-Event-Id"
+	HeaderTimestamp = "X-Sequencer-Timestamp"
+	HeaderSignature = "X-Sequencer-Signature"
+)
+
+// Webhook posts every event of a bus to one URL, in order. A delivery that fails is retried
+// with a growing delay up to WebhookMaxAttempts times before the event is skipped.
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+	// handled is the ID of the last event delivered or skipped
+	handled atomic.Uint64
+}
+
+// webhooks are the webhooks started by StartWebhooks.
+var webhooks []*Webhook
+
+// StartWebhooks starts a Webhook delivering the events of bus to each URL of WebhookURLsEnv,
+// signed with WebhookSecretEnv.
+func StartWebhooks(ctx context.Context, bus *Bus) error {
+	urls := os.Getenv(WebhookURLsEnv)
+	if urls == "" {
+		return nil
+	}
+	secret := os.Getenv(WebhookSecretEnv)
+	if secret == "" {
+		return fmt.Errorf("%s is set but %s is empty", WebhookURLsEnv, WebhookSecretEnv)
+	}
+
+	for _, url := range strings.Split(urls, ",") {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		webhook := NewWebhook(url, secret)
+		webhooks = append(webhooks, webhook)
+		go webhook.Run(ctx, bus)
+		logs.Log.Info(fmt.Sprintf("Delivering events to %s", url))
+	}
+	return nil
+}
+
+func NewWebhook(url string, secret string) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: common.WebhookTimeout * time.Second},
+	}
+}
+
+// Run delivers the events published on bus after it was called until ctx is done.
+func (w *Webhook) Run(ctx context.Context, bus *Bus) {
+	lastID := bus.LastID()
+	w.handled.Store(lastID)
+	for ctx.Err() == nil {
+		// a subscription dropped for falling behind resumes from the kept events
+		ch, cancel := bus.Subscribe(lastID)
+		lastID = w.consume(ctx, ch, lastID)
+		cancel()
+	}
+}
+
+// consume delivers the events of ch until it is closed or ctx is done, and returns the ID
+// of the last event handled.
+func (w *Webhook) consume(ctx context.Context, ch <-chan Event, lastID uint64) uint64 {
+	for {
+		select {
+		case <-ctx.Done():
+			return lastID
+		case event, ok := <-ch:
+			if !ok {
+				return lastID
+			}
+			if event.ID > lastID+1 {
+				logs.Log.Warn(fmt.Sprintf("Webhook %s missed events %d to %d", w.url, lastID+1, event.ID-1))
+			}
+			w.deliver(ctx, event)
+			lastID = event.ID
+			w.handled.Store(lastID)
+		}
+	}
+}
+
+// Flush waits until the webhooks started by StartWebhooks have handled the events published
+// on Default so far, or ctx is done. It is called before the sequencer exits.
+func Flush(ctx context.Context) {
+	lastID := Default.LastID()
+	for _, webhook := range webhooks {
+		for webhook.handled.Load() < lastID {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
+
+func (w *Webhook) deliver(ctx context.Context, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in marshalling event %d : %s", event.ID, err.Error()))
+		return
+	}
+
+	delay := time.Second
+	for attempt := 1; attempt <= common.WebhookMaxAttempts; attempt++ {
+		err = w.post(ctx, event, body)
+		if err == nil {
+			metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+			return
+		}
+		if attempt == common.WebhookMaxAttempts {
+			break
+		}
+		metrics.WebhookDeliveries.WithLabelValues("retried").Inc()
+		logs.Log.Warn(fmt.Sprintf("Error in delivering event %d to %s (attempt %d/%d) : %s", event.ID, w.url, attempt, common.WebhookMaxAttempts, err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay < time.Minute {
+			delay *= 2
+		}
+	}
+
+	metrics.WebhookDeliveries.WithLabelValues("dropped").Inc()
+	logs.Log.Error(fmt.Sprintf("Dropping event %d for %s after %d attempts : %s", event.ID, w.url, common.WebhookMaxAttempts, err.Error()))
+}
+
+func (w *Webhook) post(ctx context.Context, event Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderEventID, strconv.FormatUint(event.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(w.secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 with secret of the timestamp and body of a webhook
+// request, joined by a dot. Receivers compute it to check HeaderSignature.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -8,15 +8,21 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
 	"github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	da_client "github.com/airchains-network/evm-sequencer-node/handlers/da-client"
 	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
 	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
 	"github.com/airchains-network/evm-sequencer-node/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/syndtr/goleveldb/leveldb"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ConvertEtherToWei converts a string representation of an Ether amount to a string representation of Wei.
@@ -40,19 +46,151 @@ func ConvertEtherToWei(etherStr string) (string, error) {
 	return weiInt.String(), nil
 }
 
-func BatchGeneration(client *ethclient.Client, ctx context.Context, lds *leveldb.DB, ldt *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, batchStartIndex []byte) {
-	// defer wg.Done()
+// BatchGeneration collects batches as the sealing policy allows and runs the lifecycle stages
+// of each batch (proving, DA posting, settlement) in their own workers. Stages resume from
+// the state persisted for every batch after the last one settled. When Control changes a
+// batch, the workers finish the stage they are running and start again once it is saved.
+func BatchGeneration(client *ethclient.Client, ctx context.Context, lds *leveldb.DB, ldt *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, batchProver prover.Prover, aggregator *prover.Aggregator, daLayers *da_client.Failover, settlement settlement_client.SettlementClient) {
+	for ctx.Err() == nil {
+		runCtx, stop := context.WithCancel(ctx)
 
-	limit, err := lds.Get([]byte("batchCount"), nil)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting batchCount from static db : %s", err.Error()))
-		os.Exit(0)
+		settledCount, err := getBatchCount(lds)
+		if err != nil {
+			logs.Log.Error(fmt.Sprintf("Error in getting batchCount from static db : %s", err.Error()))
+			os.Exit(0)
+		}
+		nextBatch := restartFailedBatches(ldbatch, settledCount+1)
+
+		startIndex, err := batchTxEnd(lds, ldbatch, nextBatch-1)
+		if err != nil {
+			logs.Log.Error(fmt.Sprintf("Error in getting the first transaction of batch %d : %s", nextBatch, err.Error()))
+			os.Exit(0)
+		}
+
+		skipped, err := skippedTxs(ldbatch)
+		if err != nil {
+			logs.Log.Error(fmt.Sprintf("Error in getting skipped transactions : %s", err.Error()))
+			os.Exit(0)
+		}
+		skip := map[string]bool{}
+		for _, txHash := range skipped {
+			skip[txHash] = true
+		}
+
+		Control.run(lds, ldbatch, ldda, stop)
+
+		// stages already running finish with ctx, stop only keeps workers from taking
+		// the next batch
+		var wg sync.WaitGroup
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			collectBatches(ctx, runCtx.Done(), lds, ldt, ldbatch, nextBatch, startIndex, skip)
+		}()
+		go func() {
+			defer wg.Done()
+			proveBatches(ctx, runCtx.Done(), ldbatch, ldda, batchProver, settledCount+1)
+		}()
+		go func() {
+			defer wg.Done()
+			postBatches(ctx, runCtx.Done(), client, ldbatch, ldda, daLayers, settledCount+1)
+		}()
+		go func() {
+			defer wg.Done()
+			settleBatches(ctx, runCtx.Done(), lds, ldbatch, ldda, aggregator, settlement, settledCount+1)
+		}()
+		wg.Wait()
+		stop()
+
+		Control.applyRewind()
 	}
-	limitInt, _ := strconv.Atoi(strings.TrimSpace(string(limit)))
-	batchStartIndexInt, _ := strconv.Atoi(strings.TrimSpace(string(batchStartIndex)))
+}
+
+// collectBatches collects the transactions of every batch from firstBatch on, starting
+// after transaction startIndex and staying within collectLimit of the last settled batch,
+// until stop is closed. Transactions in skip are left out. A batch not sealed
+// when stop is closed is dropped and collected again by the next run.
+func collectBatches(ctx context.Context, stop <-chan struct{}, lds *leveldb.DB, ldt *leveldb.DB, ldbatch *leveldb.DB, firstBatch int, startIndex int, skip map[string]bool) {
+	for batchNumber := firstBatch; ; batchNumber++ {
+		logger := logs.Log.With("batch", batchNumber)
+
+		for {
+			settledCount, err := getBatchCount(lds)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Error in getting batchCount from static db : %s", err.Error()))
+				os.Exit(0)
+			}
+			if batchNumber <= collectLimit(settledCount, common.PipelineDepth, common.AggregationSize) {
+				break
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(1 * time.Second):
+			}
+		}
 
+		Control.startCollecting(batchNumber)
+		batchCtx := logs.NewContext(tracing.BatchContext(ctx, batchNumber), logger)
+		collectCtx, span := tracing.Start(batchCtx, "collect")
+		batch, endIndex, sealReason, ok := collectBatch(collectCtx, stop, ldt, startIndex, skip)
+		if !ok {
+			tracing.End(span, nil)
+			return
+		}
+		span.SetAttributes(attribute.Int("batch.transactions", endIndex-startIndex), attribute.String("batch.seal_reason", sealReason))
+		tracing.End(span, nil)
+
+		batchJSON, err := json.Marshal(batch)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in marshalling batch data : %s", err.Error()))
+			os.Exit(0)
+		}
+
+		batchKey := fmt.Sprintf("batch-%d", batchNumber)
+		err = ldbatch.Put([]byte(batchKey), batchJSON, nil)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in writing batch data to file : %s", err.Error()))
+			os.Exit(0)
+		}
+
+		state := &types.BatchStateStruct{
+			BatchNumber: batchNumber,
+			TxStart:     startIndex,
+			TxEnd:       endIndex,
+			SealReason:  sealReason,
+		}
+		advanceBatch(ldbatch, state, StageCollected)
+		events.Publish(events.TypeBatchSealed, events.BatchSealed{Batch: batchNumber, TxStart: startIndex, TxEnd: endIndex, SealReason: sealReason})
+		logger.Warn(fmt.Sprintf("Sealed Batch %d with %d transactions (%s)", batchNumber, endIndex-startIndex, sealReason))
+
+		startIndex = endIndex
+	}
+}
+
+// collectLimit returns the last batch that may be collected once settledCount batches are
+// settled: depth batches ahead, extended to the end of the aggregation range of that batch
+// since batches are only settled once their whole range is.
+func collectLimit(settledCount int, depth int, aggregationSize int) int {
+	limit := settledCount + depth
+	if aggregationSize > 0 && limit%aggregationSize != 0 {
+		limit += aggregationSize - limit%aggregationSize
+	}
+	return limit
+}
+
+// collectBatch collects the transactions after startIndex, with the balances and nonces the
+// circuit checks, until the sealing policy or Control.ForceSeal seals the batch. It returns
+// the batch, the index of its last transaction and the limit that sealed it, or false if
+// stop is closed first.
+func collectBatch(ctx context.Context, stop <-chan struct{}, ldt *leveldb.DB, startIndex int, skip map[string]bool) (types.BatchStruct, int, string, bool) {
+	logger := logs.FromContext(ctx)
+	ldb := air.GetBlockDbInstance()
 	var batch types.BatchStruct
 
+	policy := newSealPolicy()
+	var sealReason string
+
 	var From []string
 	var To []string
 	var Amounts []string
@@ -63,50 +201,95 @@ func BatchGeneration(client *ethclient.Client, ctx context.Context, lds *leveldb
 	var TransactionNonces []string
 	var AccountNonces []string
 
-	for i := batchStartIndexInt; i < (common.BatchSize * (limitInt + 1)); i++ {
+	i := startIndex
+	for {
+		select {
+		case <-stop:
+			return batch, i, "", false
+		default:
+		}
+		if sealReason = policy.full(); sealReason != "" {
+			break
+		}
+		if Control.collected(len(TransactionHash)) {
+			sealReason = SealForced
+			break
+		}
+
 		findKey := fmt.Sprintf("txns-%d", i+1)
 		txData, err := ldt.Get([]byte(findKey), nil)
 		if err != nil {
-			i--
-			time.Sleep(1 * time.Second)
+			if sealReason = policy.expired(Control.ingestionTime()); sealReason != "" {
+				break
+			}
+			select {
+			case <-stop:
+				return batch, i, "", false
+			case <-time.After(1 * time.Second):
+			}
 			continue
 		}
 		var tx types.TransactionStruct
 		err = json.Unmarshal(txData, &tx)
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in unmarshalling tx data : %s", err.Error()))
+			logger.Error(fmt.Sprintf("Error in unmarshalling tx data : %s", err.Error()))
+			os.Exit(0)
+		}
+		if skip[tx.Hash] {
+			logger.Warn(fmt.Sprintf("Skipping transaction %s", tx.Hash))
+			i++
+			continue
+		}
+
+		txGas, err := strconv.ParseUint(tx.Gas, 10, 64)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in parsing gas of tx %s : %s", tx.Hash, err.Error()))
+			os.Exit(0)
+		}
+		txTime, err := blockTime(ldb, tx.BlockNumber)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in getting time of block %d : %s", tx.BlockNumber, err.Error()))
 			os.Exit(0)
 		}
+		// a transaction made after the wait is over starts the next batch
+		if sealReason = policy.expired(txTime); sealReason != "" {
+			break
+		}
+		if sealReason = policy.exceeds(calldataSize(tx.Input), txGas); sealReason != "" {
+			break
+		}
 
+		_, stateSpan := tracing.Start(ctx, "fetch-state", attribute.String("tx.hash", tx.Hash))
 		senderBalanceInEtherCheck, err := common.GetBalance(tx.From, (tx.BlockNumber - 1))
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in getting sender balance : %s", err.Error()))
+			logger.Error(fmt.Sprintf("Error in getting sender balance : %s", err.Error()))
 			os.Exit(0)
 		}
 		// convert senderbalance from ether to wei
 		senderBalancesCheck, err := ConvertEtherToWei(senderBalanceInEtherCheck)
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in converting sender balance : %s", err.Error()))
+			logger.Error(fmt.Sprintf("Error in converting sender balance : %s", err.Error()))
 			os.Exit(0)
 		}
 
 		receiverBalancesEtherCheck, err := common.GetBalance(tx.To, (tx.BlockNumber - 1))
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in getting reciver balance : %s", err.Error()))
+			logger.Error(fmt.Sprintf("Error in getting reciver balance : %s", err.Error()))
 			os.Exit(0)
 		}
 		// convert receiverbalance from ether to wei
 		receiverBalancesCheck, err := ConvertEtherToWei(receiverBalancesEtherCheck)
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in converting receiver balance : %s", err.Error()))
+			logger.Error(fmt.Sprintf("Error in converting receiver balance : %s", err.Error()))
 			os.Exit(0)
 		}
 
 		accountNouceCheck, err := common.GetAccountNonce(ctx, tx.Hash, tx.BlockNumber)
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in getting account nonce : %s", err.Error()))
+			logger.Error(fmt.Sprintf("Error in getting account nonce : %s", err.Error()))
 			os.Exit(0)
 		}
+		tracing.End(stateSpan, nil)
 
 		From = append(From, tx.From)
 		To = append(To, tx.To)
@@ -116,12 +299,14 @@ func BatchGeneration(client *ethclient.Client, ctx context.Context, lds *leveldb
 		SenderBalances = append(SenderBalances, senderBalancesCheck)
 		ReceiverBalances = append(ReceiverBalances, receiverBalancesCheck)
 
-		fmt.Println(senderBalancesCheck)
-		fmt.Println(receiverBalancesCheck)
+		logger.Debug("Collected transaction", "tx", tx.Hash, "sender_balance", senderBalancesCheck, "receiver_balance", receiverBalancesCheck)
 
 		Messages = append(Messages, tx.Input)
 		TransactionNonces = append(TransactionNonces, tx.Nonce)
 		AccountNonces = append(AccountNonces, accountNouceCheck)
+
+		policy.add(calldataSize(tx.Input), txGas, txTime)
+		i++
 	}
 
 	batch.From = From
@@ -134,68 +319,54 @@ func BatchGeneration(client *ethclient.Client, ctx context.Context, lds *leveldb
 	batch.TransactionNonces = TransactionNonces
 	batch.AccountNonces = AccountNonces
 
-	witnessVector, currentStatusHash, proofByte, pkErr := prover.GenerateProof(batch, limitInt+1)
-	if pkErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in generating proof : %s", pkErr.Error()))
-		os.Exit(0)
-	}
+	return batch, i, sealReason, true
+}
 
-	daKeyHash, err := DaCall(batch.TransactionHash, client, ctx, currentStatusHash, limitInt+1, ldda)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in adding Da client : %s", err.Error()))
-		logs.Log.Warn("Trying again...")
-		time.Sleep(3 * time.Second)
-		BatchGeneration(client, ctx, lds, ldt, ldbatch, ldda, []byte(strconv.Itoa(common.BatchSize*(limitInt+1))))
+// batchTxEnd returns the index of the last transaction of batchNumber. For the last settled
+// batch, when it was collected before batch states were recorded, that is batchStartIndex.
+func batchTxEnd(lds *leveldb.DB, ldbatch *leveldb.DB, batchNumber int) (int, error) {
+	if batchNumber == 0 {
+		return 0, nil
 	}
-
-	logs.Log.Warn(fmt.Sprintf("Successfully added Da client for Batch %s in the latest phase", daKeyHash))
-
-	currentTime := uint64(time.Now().Unix())
-	addBatchRes := settlement_client.AddBatch(witnessVector, limitInt+1, currentStatusHash, currentTime, lds, ldda)
-	if addBatchRes == "nil" {
-		logs.Log.Error(fmt.Sprintf("Error in adding batch to settlement client : %s", addBatchRes))
-		os.Exit(0)
+	state, err := GetBatchState(ldbatch, batchNumber)
+	if err == nil {
+		return state.TxEnd, nil
 	}
-
-	status := settlement_client.VerifyBatch(limitInt+1, proofByte, ldda, lds)
-	if !status {
-		logs.Log.Error(fmt.Sprintf("Error in verifying batch to settlement client : "))
-		os.Exit(0)
+	if err != leveldb.ErrNotFound {
+		return 0, err
 	}
-
-	logs.Log.Warn(fmt.Sprintf("Successfully generated proof for Batch %s in the latest phase", strconv.Itoa(limitInt+1)))
-
-	batchJSON, err := json.Marshal(batch)
+	batchStartIndex, err := lds.Get([]byte("batchStartIndex"), nil)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling batch data : %s", err.Error()))
-		os.Exit(0)
+		return 0, err
 	}
+	return strconv.Atoi(strings.TrimSpace(string(batchStartIndex)))
+}
 
-	batchKey := fmt.Sprintf("batch-%d", limitInt+1)
-	err = ldbatch.Put([]byte(batchKey), batchJSON, nil)
+func getBatchCount(lds *leveldb.DB) (int, error) {
+	batchCount, err := lds.Get([]byte("batchCount"), nil)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in writing batch data to file : %s", err.Error()))
-		os.Exit(0)
+		return 0, err
 	}
+	return strconv.Atoi(strings.TrimSpace(string(batchCount)))
+}
 
-	err = lds.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(common.BatchSize*(limitInt+1))), nil)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in updating batchStartIndex in static db : %s", err.Error()))
-		os.Exit(0)
+// saveBatchFailure records in the batches db the stage at which batchNumber failed.
+func saveBatchFailure(ldbatch *leveldb.DB, batchNumber int, stage string, failure error) {
+	batchFailure := types.BatchFailureStruct{
+		BatchNumber: batchNumber,
+		Stage:       stage,
+		Error:       failure.Error(),
+		Timestamp:   time.Now().Unix(),
 	}
 
-	err = lds.Put([]byte("batchCount"), []byte(strconv.Itoa(limitInt+1)), nil)
+	batchFailureJSON, err := json.Marshal(batchFailure)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in updating batchCount in static db : %s", err.Error()))
-		os.Exit(0)
+		logs.Log.Error(fmt.Sprintf("Error in marshalling batch failure : %s", err.Error()))
+		return
 	}
 
-	err = os.WriteFile("data/batchCount.txt", []byte(strconv.Itoa(limitInt+1)), 0666)
+	err = ldbatch.Put([]byte(fmt.Sprintf("batch-failure-%d", batchNumber)), batchFailureJSON, nil)
 	if err != nil {
-		panic("Failed to update batch number: " + err.Error())
+		logs.Log.Error(fmt.Sprintf("Error in saving batch failure : %s", err.Error()))
 	}
-
-	logs.Log.Warn(fmt.Sprintf("Successfully saved Batch %s in the latest phase", strconv.Itoa(limitInt+1)))
-
-	BatchGeneration(client, ctx, lds, ldt, ldbatch, ldda, []byte(strconv.Itoa(common.BatchSize*(limitInt+1))))
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import "testing"
+
+func TestCollectLimit(t *testing.T) {
+	tests := []struct {
+		name            string
+		settledCount    int
+		depth           int
+		aggregationSize int
+		want            int
+	}{
+		{name: "no aggregation", settledCount: 3, depth: 4, want: 7},
+		{name: "depth ends a range", settledCount: 4, depth: 4, aggregationSize: 2, want: 8},
+		{name: "depth inside a range", settledCount: 4, depth: 3, aggregationSize: 2, want: 8},
+		// the first range must be collected in full before anything is settled
+		{name: "range larger than depth", settledCount: 0, depth: 4, aggregationSize: 8, want: 8},
+		{name: "next range larger than depth", settledCount: 8, depth: 4, aggregationSize: 8, want: 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collectLimit(tt.settledCount, tt.depth, tt.aggregationSize); got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
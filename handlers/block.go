@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -11,17 +12,26 @@ import (
 
 	"github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
 	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func BlockSave(client *ethclient.Client, ctx context.Context, blockIndex int, ldb *leveldb.DB, ldt *leveldb.DB) {
+	Control.waitWhileIngestionPaused()
+	logger := logs.Log.With("block", blockIndex)
+
 	blockData, err := client.BlockByNumber(ctx, big.NewInt(int64(blockIndex)))
 	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			Control.chainHeadReached()
+		}
 		errMessage := fmt.Sprintf("Failed to get block data for block number %d: %s", blockIndex, err)
-		logs.Log.Error(errMessage)
-		logs.Log.Info("Waiting for the next block..")
+		logger.Error(errMessage)
+		logger.Info("Waiting for the next block..")
 		time.Sleep(common.BlockDelay * time.Second)
 		BlockSave(client, ctx, blockIndex, ldb, ldt)
 	}
@@ -53,22 +63,22 @@ func BlockSave(client *ethclient.Client, ctx context.Context, blockIndex int, ld
 	data, err := json.Marshal(block)
 	if err != nil {
 		errMessage := fmt.Sprintf("Error marshalling block data: %s", err)
-		logs.Log.Error(errMessage)
+		logger.Error(errMessage)
 	}
 	key := fmt.Sprintf("block_%s", block.Number)
 	err = ldb.Put([]byte(key), data, nil)
 	if err != nil {
 		errMessage := fmt.Sprintf("Error inserting block data into database: %s", err)
-		logs.Log.Error(errMessage)
+		logger.Error(errMessage)
 	}
 
 	transactions := blockData.Transactions()
 	if transactions == nil {
-		fmt.Println("No transactions found in block number", blockIndex)
+		logger.Info("No transactions found in block")
 	}
 
 	infoMessage := fmt.Sprintf("Block number %d has %d transactions", blockIndex, transactions.Len())
-	logs.Log.Info(infoMessage)
+	logger.Info(infoMessage)
 
 	for i := 0; i < block.TransactionCount; i++ {
 		SaveTxns(client, ctx, ldt, transactions[i].Hash().String(), blockIndex, block.Hash)
@@ -76,9 +86,12 @@ func BlockSave(client *ethclient.Client, ctx context.Context, blockIndex int, ld
 
 	err = os.WriteFile("data/blockCount.txt", []byte(strconv.Itoa(blockIndex+1)), 0666)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in saving blockCount in static db : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in saving blockCount in static db : %s", err.Error()))
 		os.Exit(0)
 	}
+	Control.blockIngested(time.Unix(int64(blockData.Time()), 0))
+	metrics.IngestedBlock.Set(float64(blockIndex))
+	events.Publish(events.TypeBlockIngested, events.BlockIngested{Block: blockIndex, Hash: block.Hash, Transactions: block.TransactionCount})
 
 	BlockSave(client, ctx, blockIndex+1, ldb, ldt)
 }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// WatchChainHead records the latest block of the execution client every BlockDelay
+// seconds, so the ingested height can be compared with it.
+func WatchChainHead(ctx context.Context, client *ethclient.Client) {
+	for {
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			logs.Log.Warn(fmt.Sprintf("Error in getting the latest block : %s", err.Error()))
+		} else {
+			metrics.ChainHeadBlock.Set(float64(header.Number.Int64()))
+		}
+		time.Sleep(common.BlockDelay * time.Second)
+	}
+}
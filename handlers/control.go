@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Stages that RetryStage runs again.
+const (
+	RetryProve  = "prove"
+	RetryDA     = "da"
+	RetrySettle = "settle"
+)
+
+// SealForced is the seal reason of a batch sealed by ForceSeal.
+const SealForced = "forced"
+
+// skippedTxsKey holds, in the batches db, the hashes of the transactions left out of
+// every batch.
+const skippedTxsKey = "skipped-txs"
+
+// Controller lets operators pause ingestion and change the batch pipeline while the
+// sequencer runs. Changes to batches are applied between two runs of the pipeline: its
+// workers finish the stage they are running, the change is saved, and the workers start
+// again from the persisted batch states.
+type Controller struct {
+	ingestionPaused atomic.Bool
+	parkFailed      atomic.Bool
+
+	mu                sync.Mutex
+	ingestedBlockTime time.Time
+	chainHead         bool
+	collectingBatch   int
+	collectingTxs     int
+	forceSeal         bool
+	stop              context.CancelFunc
+	lds               *leveldb.DB
+	ldbatch           *leveldb.DB
+	ldda              *leveldb.DB
+
+	rewindMu sync.Mutex
+	rewinds  chan rewind
+}
+
+type rewind struct {
+	apply  func() error
+	result chan error
+}
+
+// Control controls the ingestion and batch pipeline of this sequencer.
+var Control = &Controller{rewinds: make(chan rewind, 1)}
+
+// ControlStatus is the state of ingestion and of the batches that are not settled.
+type ControlStatus struct {
+	IngestionPaused bool                     `json:"ingestion_paused"`
+	SettledBatch    int                      `json:"settled_batch"`
+	CollectingBatch int                      `json:"collecting_batch"`
+	CollectingTxs   int                      `json:"collecting_txs"`
+	Batches         []types.BatchStateStruct `json:"batches"`
+	SkippedTxs      []string                 `json:"skipped_txs"`
+}
+
+// PauseIngestion stops saving blocks after the block being saved.
+func (c *Controller) PauseIngestion() {
+	c.ingestionPaused.Store(true)
+}
+
+// ResumeIngestion saves blocks again from the first block not saved.
+func (c *Controller) ResumeIngestion() {
+	c.ingestionPaused.Store(false)
+}
+
+func (c *Controller) IngestionPaused() bool {
+	return c.ingestionPaused.Load()
+}
+
+// blockIngested records the time of the latest block saved.
+func (c *Controller) blockIngested(blockTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ingestedBlockTime = blockTime
+}
+
+// chainHeadReached records that every block of the chain has been saved, after which
+// blocks are saved as they are produced.
+func (c *Controller) chainHeadReached() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chainHead = true
+}
+
+// ingestionTime is the time up to which transactions have been saved: the wall-clock once
+// ingestion has reached the chain head, and the time of the latest block saved while it
+// catches up, so batches are not sealed by BatchMaxWait for blocks not saved yet.
+func (c *Controller) ingestionTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.chainHead {
+		return time.Now()
+	}
+	return c.ingestedBlockTime
+}
+
+// ParkFailedBatches keeps the sequencer running when a batch fails, with the workers
+// waiting on the failed batch until it is retried or failed. It is set once an operator
+// can do so through the admin API.
+func (c *Controller) ParkFailedBatches() {
+	c.parkFailed.Store(true)
+}
+
+func (c *Controller) ParksFailedBatches() bool {
+	return c.parkFailed.Load()
+}
+
+// waitWhileIngestionPaused blocks while ingestion is paused.
+func (c *Controller) waitWhileIngestionPaused() {
+	for c.IngestionPaused() {
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// Status returns the state of ingestion and of every batch after the last settled one.
+func (c *Controller) Status() (*ControlStatus, error) {
+	c.mu.Lock()
+	status := &ControlStatus{
+		IngestionPaused: c.IngestionPaused(),
+		CollectingBatch: c.collectingBatch,
+		CollectingTxs:   c.collectingTxs,
+	}
+	lds, ldbatch := c.lds, c.ldbatch
+	c.mu.Unlock()
+
+	if lds == nil {
+		return nil, errors.New("batch pipeline is not running")
+	}
+
+	settledCount, err := getBatchCount(lds)
+	if err != nil {
+		return nil, err
+	}
+	status.SettledBatch = settledCount
+	status.Batches = []types.BatchStateStruct{}
+	for batchNumber := settledCount + 1; ; batchNumber++ {
+		state, err := GetBatchState(ldbatch, batchNumber)
+		if err != nil {
+			break
+		}
+		status.Batches = append(status.Batches, *state)
+	}
+
+	status.SkippedTxs, err = skippedTxs(ldbatch)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ForceSeal seals the batch being collected with the transactions it already holds. It
+// returns the number of the batch and of its transactions.
+func (c *Controller) ForceSeal() (int, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.collectingTxs == 0 {
+		return 0, 0, fmt.Errorf("batch %d has no transactions to seal", c.collectingBatch)
+	}
+	c.forceSeal = true
+	return c.collectingBatch, c.collectingTxs, nil
+}
+
+// startCollecting records that batchNumber is being collected.
+func (c *Controller) startCollecting(batchNumber int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collectingBatch = batchNumber
+	c.collectingTxs = 0
+	c.forceSeal = false
+}
+
+// collected records that the batch being collected holds txs transactions, and reports
+// whether it should be sealed now.
+func (c *Controller) collected(txs int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collectingTxs = txs
+	return c.forceSeal && txs > 0
+}
+
+// RetryStage runs stage again for batchNumber, which has not been submitted past that
+// stage. RetryProve proves the batch again, RetryDA posts it to DA again and RetrySettle
+// adds and verifies its pod again.
+func (c *Controller) RetryStage(batchNumber int, stage string) error {
+	return c.rewind(func() error {
+		state, err := c.unsettledBatch(batchNumber)
+		if err != nil {
+			return err
+		}
+
+		switch stage {
+		case RetryProve:
+			if !stageReached(state.Stage, StageCollected) || stageReached(state.Stage, StageSubmitted) {
+				return fmt.Errorf("batch %d is %s, only batches collected and not submitted can be proved again", batchNumber, state.Stage)
+			}
+			if err := prover.DeleteProofResult(batchNumber); err != nil {
+				return err
+			}
+			state.Stage = StageCollected
+		case RetryDA:
+			if !stageReached(state.Stage, StageProved) || stageReached(state.Stage, StageSubmitted) {
+				return fmt.Errorf("batch %d is %s, only batches proved and not submitted can be posted again", batchNumber, state.Stage)
+			}
+			if err := c.ldda.Delete([]byte(fmt.Sprintf("batch_%d", batchNumber)), nil); err != nil {
+				return err
+			}
+			state.DAKey = ""
+			state.Stage = StageProved
+		case RetrySettle:
+			if !stageReached(state.Stage, StageDAPosted) {
+				return fmt.Errorf("batch %d is %s, only batches posted to DA can be settled again", batchNumber, state.Stage)
+			}
+			state.SettlementResponse = ""
+			state.Stage = StageDAPosted
+		default:
+			return fmt.Errorf("unknown stage %q", stage)
+		}
+
+		state.FailedStage = ""
+		state.ResumeStage = ""
+		state.Error = ""
+
+		logs.Log.With("batch", batchNumber).Warn(fmt.Sprintf("Retrying stage %s of batch %d", stage, batchNumber))
+		return putBatchState(c.ldbatch, state)
+	})
+}
+
+// FailBatch abandons batchNumber and the batches collected after it, none of which may be
+// submitted yet. Their transactions are collected again into new batches, leaving out
+// skipTxs.
+func (c *Controller) FailBatch(batchNumber int, reason string, skipTxs []string) error {
+	return c.rewind(func() error {
+		if _, err := c.unsettledBatch(batchNumber); err != nil {
+			return err
+		}
+
+		var abandoned []int
+		for n := batchNumber; ; n++ {
+			state, err := GetBatchState(c.ldbatch, n)
+			if err == leveldb.ErrNotFound {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if stageReached(state.Stage, StageSubmitted) {
+				return fmt.Errorf("batch %d is already submitted to the settlement layer", n)
+			}
+			abandoned = append(abandoned, n)
+		}
+
+		if len(skipTxs) > 0 {
+			skipped, err := skippedTxs(c.ldbatch)
+			if err != nil {
+				return err
+			}
+			skippedJSON, err := json.Marshal(append(skipped, skipTxs...))
+			if err != nil {
+				return err
+			}
+			if err := c.ldbatch.Put([]byte(skippedTxsKey), skippedJSON, nil); err != nil {
+				return err
+			}
+		}
+
+		failure := fmt.Errorf("marked failed by operator: %s", reason)
+		saveBatchFailure(c.ldbatch, batchNumber, "admin", failure)
+		for _, n := range abandoned {
+			if err := c.ldbatch.Delete([]byte(fmt.Sprintf("batch-state-%d", n)), nil); err != nil {
+				return err
+			}
+			tracing.EndBatch(n, failure)
+			events.Publish(events.TypeBatchFailed, events.BatchFailed{Batch: n, Stage: "admin", Error: failure.Error()})
+		}
+
+		logs.Log.With("batch", batchNumber).Warn(fmt.Sprintf("Batch %d marked failed, collecting %d batches again : %s", batchNumber, len(abandoned), reason))
+		return nil
+	})
+}
+
+// unsettledBatch returns the state of batchNumber, which must not be settled.
+func (c *Controller) unsettledBatch(batchNumber int) (*types.BatchStateStruct, error) {
+	settledCount, err := getBatchCount(c.lds)
+	if err != nil {
+		return nil, err
+	}
+	if batchNumber <= settledCount {
+		return nil, fmt.Errorf("batch %d is already settled", batchNumber)
+	}
+	state, err := GetBatchState(c.ldbatch, batchNumber)
+	if err != nil {
+		return nil, fmt.Errorf("batch %d is not collected: %w", batchNumber, err)
+	}
+	if state.Stage == StageFailed {
+		state.Stage = state.ResumeStage
+		if state.Stage == "" {
+			state.Stage = StageCollected
+		}
+	}
+	return state, nil
+}
+
+// rewind stops the pipeline, runs apply once its workers have stopped and returns the
+// error of apply. The pipeline starts again either way.
+func (c *Controller) rewind(apply func() error) error {
+	c.rewindMu.Lock()
+	defer c.rewindMu.Unlock()
+
+	c.mu.Lock()
+	stop := c.stop
+	c.mu.Unlock()
+	if stop == nil {
+		return errors.New("batch pipeline is not running")
+	}
+
+	r := rewind{apply: apply, result: make(chan error, 1)}
+	c.rewinds <- r
+	stop()
+	return <-r.result
+}
+
+// run records the pipeline run stopped by stop.
+func (c *Controller) run(lds *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, stop context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lds, c.ldbatch, c.ldda = lds, ldbatch, ldda
+	c.stop = stop
+}
+
+// applyRewind applies the change that stopped the pipeline, if any.
+func (c *Controller) applyRewind() {
+	c.mu.Lock()
+	c.stop = nil
+	c.mu.Unlock()
+
+	select {
+	case r := <-c.rewinds:
+		r.result <- r.apply()
+	default:
+	}
+}
+
+// skippedTxs returns the hashes of the transactions left out of batches.
+func skippedTxs(ldbatch *leveldb.DB) ([]string, error) {
+	skippedJSON, err := ldbatch.Get([]byte(skippedTxsKey), nil)
+	if err == leveldb.ErrNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var skipped []string
+	if err := json.Unmarshal(skippedJSON, &skipped); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// runningController returns a Controller of a pipeline that has settled batch 1 and
+// applies a change each time it is stopped.
+func runningController(t *testing.T, states ...types.BatchStateStruct) (*Controller, *leveldb.DB, *leveldb.DB) {
+	t.Helper()
+	lds, ldbatch, ldda := memDB(t), memDB(t), memDB(t)
+	lds.Put([]byte("batchCount"), []byte("1"), nil)
+	for i := range states {
+		if err := putBatchState(ldbatch, &states[i]); err != nil {
+			t.Fatal(err)
+		}
+		ldda.Put([]byte(fmt.Sprintf("batch_%d", states[i].BatchNumber)), []byte("{}"), nil)
+	}
+
+	c := &Controller{rewinds: make(chan rewind, 1)}
+	c.run(lds, ldbatch, ldda, func() { go c.applyRewind() })
+	return c, ldbatch, ldda
+}
+
+func TestRetryStage(t *testing.T) {
+	failedAtDA := types.BatchStateStruct{BatchNumber: 2, Stage: StageFailed, ResumeStage: StageProved, FailedStage: "da-verification", Error: "not on DA"}
+	failedAtSettle := types.BatchStateStruct{BatchNumber: 2, Stage: StageFailed, ResumeStage: StageSubmitted, FailedStage: "settlement-verify", Error: "rejected"}
+	failedAtCollect := types.BatchStateStruct{BatchNumber: 2, Stage: StageFailed, FailedStage: "proving", Error: "out of memory"}
+
+	tests := []struct {
+		name      string
+		state     types.BatchStateStruct
+		batch     int
+		stage     string
+		wantStage string
+		wantErr   string
+	}{
+		{name: "failed at da", state: failedAtDA, batch: 2, stage: RetryDA, wantStage: StageProved},
+		{name: "failed at settlement", state: failedAtSettle, batch: 2, stage: RetrySettle, wantStage: StageDAPosted},
+		{name: "da of a submitted batch", state: failedAtSettle, batch: 2, stage: RetryDA, wantErr: "only batches proved and not submitted"},
+		{name: "da of a batch failed before proving", state: failedAtCollect, batch: 2, stage: RetryDA, wantErr: "only batches proved"},
+		{name: "settled batch", state: failedAtDA, batch: 1, stage: RetryDA, wantErr: "already settled"},
+		{name: "not collected", state: failedAtDA, batch: 3, stage: RetryDA, wantErr: "not collected"},
+		{name: "unknown stage", state: failedAtDA, batch: 2, stage: "collect", wantErr: "unknown stage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ldbatch, ldda := runningController(t, tt.state)
+
+			err := c.RetryStage(tt.batch, tt.stage)
+			state, getErr := GetBatchState(ldbatch, 2)
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				if state.Stage != StageFailed || state.Error != tt.state.Error {
+					t.Fatalf("refused retry changed the batch to %+v", state)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Stage != tt.wantStage || state.FailedStage != "" || state.ResumeStage != "" || state.Error != "" {
+				t.Fatalf("batch is %+v, want stage %s without failure", state, tt.wantStage)
+			}
+			if _, err := ldda.Get([]byte("batch_2"), nil); (err == leveldb.ErrNotFound) != (tt.stage == RetryDA) {
+				t.Fatalf("DA record of batch 2: %v", err)
+			}
+		})
+	}
+}
+
+func TestControllerFailBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		states  []types.BatchStateStruct
+		wantErr string
+	}{
+		{
+			name: "abandons later batches",
+			states: []types.BatchStateStruct{
+				{BatchNumber: 2, Stage: StageFailed, ResumeStage: StageProved, FailedStage: "da-submission"},
+				{BatchNumber: 3, Stage: StageCollected},
+			},
+		},
+		{
+			name: "later batch submitted",
+			states: []types.BatchStateStruct{
+				{BatchNumber: 2, Stage: StageProved},
+				{BatchNumber: 3, Stage: StageSubmitted},
+			},
+			wantErr: "already submitted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ldbatch, _ := runningController(t, tt.states...)
+
+			err := c.FailBatch(2, "bad transaction", []string{"0xabc"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				if _, err := GetBatchState(ldbatch, 2); err != nil {
+					t.Fatalf("refused fail removed batch 2: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, state := range tt.states {
+				if _, err := GetBatchState(ldbatch, state.BatchNumber); err != leveldb.ErrNotFound {
+					t.Fatalf("batch %d was not abandoned: %v", state.BatchNumber, err)
+				}
+			}
+			skipped, err := skippedTxs(ldbatch)
+			if err != nil || len(skipped) != 1 || skipped[0] != "0xabc" {
+				t.Fatalf("skipped transactions %v, %v", skipped, err)
+			}
+		})
+	}
+}
+
+func TestControllerNotRunning(t *testing.T) {
+	c := &Controller{rewinds: make(chan rewind, 1)}
+	if err := c.RetryStage(2, RetryDA); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestFailedBatchParksWorkers(t *testing.T) {
+	Control.ParkFailedBatches()
+	t.Cleanup(func() { Control.parkFailed.Store(false) })
+
+	ldbatch := memDB(t)
+	state := types.BatchStateStruct{BatchNumber: 2, Stage: StageProved}
+	if err := putBatchState(ldbatch, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	failBatch(ldbatch, 2, "da-submission", errors.New("every DA layer failed"))
+	failed, err := GetBatchState(ldbatch, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Stage != StageFailed || failed.ResumeStage != StageProved || failed.FailedStage != "da-submission" {
+		t.Fatalf("failed batch is %+v", failed)
+	}
+
+	// a worker waiting on the failed batch neither goes on nor stops the sequencer
+	stop := make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		_, ok := waitForStage(stop, ldbatch, 2, StageProved)
+		done <- ok
+	}()
+	select {
+	case <-done:
+		t.Fatal("worker went on with a failed batch")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(stop)
+	if <-done {
+		t.Fatal("worker went on with a failed batch")
+	}
+
+	// a restart resumes the batch after the last stage it completed
+	if next := restartFailedBatches(ldbatch, 2); next != 3 {
+		t.Fatalf("first uncollected batch is %d, want 3", next)
+	}
+	resumed, err := GetBatchState(ldbatch, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Stage != StageProved || resumed.FailedStage != "" || resumed.ResumeStage != "" || resumed.Error != "" {
+		t.Fatalf("batch 2 is %+v after a restart, want stage %s without failure", resumed, StageProved)
+	}
+}
@@ -0,0 +1,143 @@
+package da_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// AvailClient submits blobs through the HTTP API (v2) of an Avail light client. Keys have
+// the form "<block number>:<extrinsic hash>".
+type AvailClient struct {
+	rpc    string
+	appID  int
+	client *http.Client
+}
+
+func NewAvailClient(rpc string, appID int) *AvailClient {
+	return &AvailClient{rpc: strings.TrimRight(rpc, "/"), appID: appID, client: &http.Client{}}
+}
+
+func (c *AvailClient) Name() string {
+	return Avail
+}
+
+type availSubmitResponse struct {
+	BlockNumber uint64 `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+	Hash        string `json:"hash"`
+	Index       uint32 `json:"index"`
+}
+
+type availBlockDataResponse struct {
+	DataTransactions []struct {
+		Data      string `json:"data"`
+		Extrinsic string `json:"extrinsic"`
+	} `json:"data_transactions"`
+}
+
+func (c *AvailClient) Submit(ctx context.Context, blob []byte) (string, error) {
+	body, err := json.Marshal(map[string]string{"data": base64.StdEncoding.EncodeToString(blob)})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v2/submit", c.rpc), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("avail light client returned status %d", res.StatusCode)
+	}
+
+	var response availSubmitResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	if _, err := decodeAvailHash(response.Hash); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d:%s", response.BlockNumber, strings.ToLower(response.Hash)), nil
+}
+
+// Get returns the data transaction of the block whose extrinsic hashes to the hash in key.
+// The index returned on submission counts every extrinsic of the block while the light
+// client only lists the data transactions of its app id, so blobs are found by hash.
+func (c *AvailClient) Get(ctx context.Context, key string) ([]byte, error) {
+	blockNumber, hash, err := parseAvailKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v2/blocks/%d/data?fields=data,extrinsic", c.rpc, blockNumber)
+	body, err := httpGet(ctx, c.client, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var response availBlockDataResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	for _, tx := range response.DataTransactions {
+		extrinsic, err := base64.StdEncoding.DecodeString(tx.Extrinsic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extrinsic in avail block %d: %w", blockNumber, err)
+		}
+		if extrinsicHash := blake2b.Sum256(extrinsic); bytes.Equal(extrinsicHash[:], hash) {
+			return base64.StdEncoding.DecodeString(tx.Data)
+		}
+	}
+
+	return nil, fmt.Errorf("avail block %d has no data transaction with hash 0x%x", blockNumber, hash)
+}
+
+func (c *AvailClient) Status(ctx context.Context) error {
+	_, err := httpGet(ctx, c.client, fmt.Sprintf("%s/v2/status", c.rpc))
+	return err
+}
+
+func parseAvailKey(key string) (uint64, []byte, error) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 2 {
+		return 0, nil, fmt.Errorf("invalid avail key %q", key)
+	}
+
+	blockNumber, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid avail key %q: %w", key, err)
+	}
+	hash, err := decodeAvailHash(parts[1])
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid avail key %q: %w", key, err)
+	}
+
+	return blockNumber, hash, nil
+}
+
+// decodeAvailHash decodes a 0x prefixed 32 byte extrinsic hash.
+func decodeAvailHash(hash string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+	if err != nil || len(decoded) != blake2b.Size256 {
+		return nil, fmt.Errorf("invalid extrinsic hash %q", hash)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,84 @@
+package da_client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// availLightClient serves /v2/blocks/<n>/data with data transactions of other extrinsics
+// before the one submitted, as a block holding other apps' transactions would.
+func availLightClient(t *testing.T, blockNumber uint64, txs map[string][]byte) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/v2/blocks/%d/data", blockNumber) {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("fields") != "data,extrinsic" {
+			t.Errorf("unexpected fields %q", r.URL.Query().Get("fields"))
+		}
+		type dataTx struct {
+			Data      string `json:"data"`
+			Extrinsic string `json:"extrinsic"`
+		}
+		var response struct {
+			DataTransactions []dataTx `json:"data_transactions"`
+		}
+		for extrinsic, data := range txs {
+			response.DataTransactions = append(response.DataTransactions, dataTx{
+				Data:      base64.StdEncoding.EncodeToString(data),
+				Extrinsic: base64.StdEncoding.EncodeToString([]byte(extrinsic)),
+			})
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestAvailGetByExtrinsicHash(t *testing.T) {
+	server := availLightClient(t, 42, map[string][]byte{
+		"extrinsic-a": []byte("other blob"),
+		"extrinsic-b": []byte("our blob"),
+	})
+	defer server.Close()
+	client := NewAvailClient(server.URL, 1)
+
+	hash := blake2b.Sum256([]byte("extrinsic-b"))
+	missing := blake2b.Sum256([]byte("extrinsic-c"))
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr string
+	}{
+		{"found", fmt.Sprintf("42:0x%x", hash), "our blob", ""},
+		{"not in block", fmt.Sprintf("42:0x%x", missing), "", "no data transaction with hash"},
+		{"index key", "42:1", "", "invalid avail key"},
+		{"no block", fmt.Sprintf("0x%x", hash), "", "invalid avail key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := client.Get(context.Background(), tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(blob) != tt.want {
+				t.Fatalf("got %q, want %q", blob, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package da_client
+
+import (
+	"fmt"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// BlobVersion is the encoding of types.DABatchBlob written by EncodeBatchBlob: a version
+// byte, a codec byte and the RLP of the blob compressed with that codec. Version 1 blobs
+// have no codec byte and are always gzip compressed.
+const BlobVersion = 2
+
+// EncodeBatchBlob encodes blob in the current BlobVersion, compressed with codec. It also
+// returns the size of the uncompressed encoding.
+func EncodeBatchBlob(blob *types.DABatchBlob, codec string) ([]byte, int, error) {
+	codecID, ok := codecIDs[codec]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported compression codec %q", codec)
+	}
+
+	encoded, err := rlp.EncodeToBytes(blob)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	compressed, err := compress(codec, encoded)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return append([]byte{BlobVersion, codecID}, compressed...), len(encoded), nil
+}
+
+// DecodeBatchBlob decodes a blob written by EncodeBatchBlob.
+func DecodeBatchBlob(data []byte) (*types.DABatchBlob, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty batch blob")
+	}
+
+	var codec string
+	var compressed []byte
+	switch data[0] {
+	case 1:
+		codec, compressed = CodecGzip, data[1:]
+	case BlobVersion:
+		if len(data) < 2 {
+			return nil, fmt.Errorf("truncated batch blob")
+		}
+		name, err := codecName(data[1])
+		if err != nil {
+			return nil, err
+		}
+		codec, compressed = name, data[2:]
+	default:
+		return nil, fmt.Errorf("unsupported batch blob version %d", data[0])
+	}
+
+	encoded, err := decompress(codec, compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	var blob types.DABatchBlob
+	if err := rlp.DecodeBytes(encoded, &blob); err != nil {
+		return nil, err
+	}
+
+	return &blob, nil
+}
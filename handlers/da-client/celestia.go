@@ -0,0 +1,93 @@
+package da_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+// DaClientRPCEnv is the environment variable that overrides the address of the Celestia
+// DA proxy, common.DaClientRPC.
+const DaClientRPCEnv = "DA_CLIENT_RPC"
+
+// CelestiaClient talks to the Celestia DA proxy, which takes the JSON upload of a batch
+// and answers with the key of the blob.
+type CelestiaClient struct {
+	rpc    string
+	client *http.Client
+}
+
+func NewCelestiaClient(rpc string) *CelestiaClient {
+	return &CelestiaClient{rpc: strings.TrimRight(rpc, "/"), client: &http.Client{}}
+}
+
+func (c *CelestiaClient) Name() string {
+	return Celestia
+}
+
+func (c *CelestiaClient) Submit(ctx context.Context, blob []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpc, bytes.NewReader(blob))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("celestia proxy returned status %d", res.StatusCode)
+	}
+
+	var response types.DAResponseStruct
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", err
+	}
+	if response.DaKeyHash == "" || response.DaKeyHash == "nil" {
+		return "", fmt.Errorf("celestia proxy did not return a key: %s", response.Message)
+	}
+
+	return response.DaKeyHash, nil
+}
+
+func (c *CelestiaClient) Get(ctx context.Context, key string) ([]byte, error) {
+	return httpGet(ctx, c.client, fmt.Sprintf("%s/%s", c.rpc, key))
+}
+
+func (c *CelestiaClient) Status(ctx context.Context) error {
+	_, err := httpGet(ctx, c.client, fmt.Sprintf("%s/status", c.rpc))
+	return err
+}
+
+// httpGet returns the body of a GET request to url, failing on any status other than 200.
+func httpGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d: %s", url, res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return body, nil
+}
@@ -0,0 +1,167 @@
+package da_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// CelestiaAuthTokenEnv is the environment variable holding the auth token of the Celestia
+// node, as printed by "celestia <node> auth write".
+const CelestiaAuthTokenEnv = "CELESTIA_NODE_AUTH_TOKEN"
+
+// CelestiaNodeClient submits blobs through the JSON-RPC API of a Celestia node, which pays for
+// them with its own account. Keys have the form "<height>:<hex blob commitment>".
+type CelestiaNodeClient struct {
+	rpc       string
+	namespace []byte
+	token     string
+	client    *http.Client
+	nextID    atomic.Int64
+}
+
+// NewCelestiaNodeClient returns a client of the Celestia node at rpc posting under the version
+// 0 namespace with the 10 byte ID namespaceID, given in hex.
+func NewCelestiaNodeClient(rpc string, namespaceID string, token string) (*CelestiaNodeClient, error) {
+	id, err := hex.DecodeString(namespaceID)
+	if err != nil || len(id) != 10 {
+		return nil, fmt.Errorf("celestia namespace ID must be 10 bytes of hex, got %q", namespaceID)
+	}
+	// version 0 namespaces are a zero version byte, 18 zero bytes and the ID
+	namespace := append(make([]byte, 19), id...)
+	return &CelestiaNodeClient{rpc: strings.TrimRight(rpc, "/"), namespace: namespace, token: token, client: &http.Client{}}, nil
+}
+
+func (c *CelestiaNodeClient) Name() string {
+	return CelestiaNode
+}
+
+type celestiaBlob struct {
+	Namespace    []byte `json:"namespace"`
+	Data         []byte `json:"data"`
+	ShareVersion uint32 `json:"share_version"`
+	Commitment   []byte `json:"commitment,omitempty"`
+}
+
+type celestiaRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// call sends the JSON-RPC request method with params and decodes its result into result.
+func (c *CelestiaNodeClient) call(ctx context.Context, method string, result any, params ...any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.nextID.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpc, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("celestia node returned status %d for %s: %s", res.StatusCode, method, bytes.TrimSpace(resBody))
+	}
+
+	var response struct {
+		Result json.RawMessage   `json:"result"`
+		Error  *celestiaRPCError `json:"error"`
+	}
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		return fmt.Errorf("error decoding celestia %s response: %w", method, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("celestia %s failed: %s (code %d)", method, response.Error.Message, response.Error.Code)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// Submit posts blob with blob.Submit, which only returns the height it was included at,
+// and reads the commitment of the blob back from that height.
+func (c *CelestiaNodeClient) Submit(ctx context.Context, blob []byte) (string, error) {
+	var height uint64
+	// null options use the default gas price of the node
+	err := c.call(ctx, "blob.Submit", &height, []celestiaBlob{{Namespace: c.namespace, Data: blob}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var included []celestiaBlob
+	if err := c.call(ctx, "blob.GetAll", &included, height, [][]byte{c.namespace}); err != nil {
+		return "", fmt.Errorf("error reading blobs at height %d: %w", height, err)
+	}
+	for _, b := range included {
+		if bytes.Equal(b.Data, blob) && len(b.Commitment) > 0 {
+			return fmt.Sprintf("%d:%x", height, b.Commitment), nil
+		}
+	}
+
+	return "", fmt.Errorf("blob not found at celestia height %d", height)
+}
+
+func (c *CelestiaNodeClient) Get(ctx context.Context, key string) ([]byte, error) {
+	height, commitment, err := parseCelestiaKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var blob celestiaBlob
+	if err := c.call(ctx, "blob.Get", &blob, height, c.namespace, commitment); err != nil {
+		return nil, err
+	}
+
+	return blob.Data, nil
+}
+
+// Status fails when the node cannot return its local header, e.g. while it is starting.
+func (c *CelestiaNodeClient) Status(ctx context.Context) error {
+	return c.call(ctx, "header.LocalHead", nil)
+}
+
+func parseCelestiaKey(key string) (uint64, []byte, error) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 2 {
+		return 0, nil, fmt.Errorf("invalid celestia key %q", key)
+	}
+
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid celestia key %q: %w", key, err)
+	}
+	commitment, err := hex.DecodeString(parts[1])
+	if err != nil || len(commitment) == 0 {
+		return 0, nil, fmt.Errorf("invalid celestia key %q", key)
+	}
+
+	return height, commitment, nil
+}
@@ -0,0 +1,118 @@
+package da_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// celestiaNode answers blob.Submit, blob.GetAll, blob.Get and header.LocalHead for blobs
+// held in memory at height 7.
+type celestiaNode struct {
+	t     *testing.T
+	token string
+	blobs []celestiaBlob
+}
+
+func (n *celestiaNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+n.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		ID     int64             `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		n.t.Fatal(err)
+	}
+
+	var result any
+	switch req.Method {
+	case "blob.Submit":
+		var blobs []celestiaBlob
+		json.Unmarshal(req.Params[0], &blobs)
+		for _, b := range blobs {
+			// a stand-in for the share commitment
+			b.Commitment = append([]byte("c-"), b.Data...)
+			n.blobs = append(n.blobs, b)
+		}
+		result = 7
+	case "blob.GetAll":
+		result = n.blobs
+	case "blob.Get":
+		var commitment []byte
+		json.Unmarshal(req.Params[2], &commitment)
+		for _, b := range n.blobs {
+			if bytes.Equal(b.Commitment, commitment) {
+				result = b
+			}
+		}
+		if result == nil {
+			json.NewEncoder(w).Encode(map[string]any{"id": req.ID, "error": map[string]any{"code": 1, "message": "blob: not found"}})
+			return
+		}
+	case "header.LocalHead":
+		result = map[string]any{"header": map[string]any{"height": "7"}}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"id": req.ID, "result": result})
+}
+
+func TestCelestiaNodeRoundTrip(t *testing.T) {
+	node := &celestiaNode{t: t, token: "tok", blobs: []celestiaBlob{{Data: []byte("other"), Commitment: []byte("c-other")}}}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	client, err := NewCelestiaNodeClient(server.URL, "00616972636861696e73", "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := client.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.Submit(ctx, []byte("batch blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "7:") {
+		t.Fatalf("key %q is not at height 7", key)
+	}
+	blob, err := client.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(blob) != "batch blob" {
+		t.Fatalf("got %q", blob)
+	}
+
+	if _, err := client.Get(ctx, "7:00"); err == nil || !strings.Contains(err.Error(), "blob: not found") {
+		t.Fatalf("got error %v for a missing blob", err)
+	}
+
+	unauthorized, _ := NewCelestiaNodeClient(server.URL, "00616972636861696e73", "wrong")
+	if err := unauthorized.Status(ctx); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Fatalf("got error %v without the token", err)
+	}
+}
+
+func TestNewCelestiaNodeClientNamespace(t *testing.T) {
+	for _, id := range []string{"", "0061", "zz616972636861696e73", "0000616972636861696e73"} {
+		if _, err := NewCelestiaNodeClient("http://node", id, ""); err == nil {
+			t.Errorf("namespace ID %q was accepted", id)
+		}
+	}
+	client, err := NewCelestiaNodeClient("http://node", "00616972636861696e73", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.namespace) != 29 || !bytes.Equal(client.namespace[19:], []byte("\x00airchains")) {
+		t.Fatalf("unexpected namespace %x", client.namespace)
+	}
+}
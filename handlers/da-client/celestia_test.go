@@ -0,0 +1,80 @@
+package da_client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+func TestCelestiaProxyRoundTrip(t *testing.T) {
+	blobs := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/celestia":
+			body, _ := io.ReadAll(r.Body)
+			blobs["k1"] = body
+			json.NewEncoder(w).Encode(types.DAResponseStruct{DaKeyHash: "k1"})
+		case r.URL.Path == "/celestia/status":
+		case r.URL.Path == "/celestia/k1":
+			w.Write(blobs["k1"])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// the trailing slash of DA_CLIENT_RPC in .env is dropped
+	client := NewCelestiaClient(server.URL + "/celestia/")
+	ctx := context.Background()
+
+	if err := client.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.Submit(ctx, []byte(`{"blob":"b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := client.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(blob) != `{"blob":"b"}` {
+		t.Fatalf("got %q", blob)
+	}
+	if _, err := client.Get(ctx, "k2"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("got error %v for a missing blob", err)
+	}
+}
+
+func TestNewCelestiaKinds(t *testing.T) {
+	t.Setenv(DaClientRPCEnv, "http://proxy:5050/celestia/")
+	tests := []struct {
+		daSelected string
+		want       string
+	}{
+		{"Celestia", Celestia},
+		{"Celestia-Node", CelestiaNode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.daSelected, func(t *testing.T) {
+			client, err := New(tt.daSelected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.Name() != tt.want {
+				t.Fatalf("got %s, want %s", client.Name(), tt.want)
+			}
+		})
+	}
+
+	client, _ := New(Celestia)
+	if rpc := client.(instrumented).DAClient.(*CelestiaClient).rpc; rpc != "http://proxy:5050/celestia" {
+		t.Fatalf("proxy at %s, want the address in %s", rpc, DaClientRPCEnv)
+	}
+}
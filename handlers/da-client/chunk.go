@@ -0,0 +1,160 @@
+package da_client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+// SubmitBatch publishes upload with blob attached, signed with signer unless signer is nil.
+// maxBlobSize bounds the JSON actually submitted: when upload with blob is larger, blob is
+// split into chunks, each submitted as a JSON chunk of at most maxBlobSize bytes, and upload
+// carries a manifest of their keys instead of the blob. It returns the key of upload and
+// the number of submissions.
+func SubmitBatch(ctx context.Context, client DAClient, signer *keyring.Key, upload types.DAUploadStruct, blob []byte, maxBlobSize int) (string, int, error) {
+	submissions := 1
+
+	upload.Blob = blob
+	uploadJSON, err := marshalUpload(signer, &upload)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if len(uploadJSON) > maxBlobSize {
+		hash := sha256.Sum256(blob)
+		manifest := &types.DAManifestStruct{
+			Size: len(blob),
+			Hash: hex.EncodeToString(hash[:]),
+		}
+
+		for offset := 0; offset < len(blob); {
+			chunk := types.DAChunkStruct{
+				Version:     upload.Version,
+				BatchNumber: upload.MetaData.BatchNumber,
+				Index:       len(manifest.Chunks),
+			}
+			size, err := chunkDataSize(chunk, maxBlobSize)
+			if err != nil {
+				return "", 0, err
+			}
+			end := offset + size
+			if end > len(blob) {
+				end = len(blob)
+			}
+			chunk.Data = blob[offset:end]
+
+			chunkJSON, err := json.Marshal(chunk)
+			if err != nil {
+				return "", 0, err
+			}
+
+			key, err := client.Submit(ctx, chunkJSON)
+			if err != nil {
+				return "", 0, fmt.Errorf("error submitting chunk %d: %w", len(manifest.Chunks), err)
+			}
+			manifest.Chunks = append(manifest.Chunks, key)
+			submissions++
+			offset = end
+		}
+
+		upload.Blob = nil
+		upload.Manifest = manifest
+		uploadJSON, err = marshalUpload(signer, &upload)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(uploadJSON) > maxBlobSize {
+			return "", 0, fmt.Errorf("batch upload is %d bytes without its blob, above the limit of %d", len(uploadJSON), maxBlobSize)
+		}
+	}
+
+	key, err := client.Submit(ctx, uploadJSON)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return key, submissions, nil
+}
+
+// marshalUpload signs upload with key, unless key is nil, and returns its JSON.
+func marshalUpload(key *keyring.Key, upload *types.DAUploadStruct) ([]byte, error) {
+	if key != nil {
+		if err := signUpload(key, upload); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(upload)
+}
+
+// chunkDataSize returns how many bytes of blob fit in chunk so that its JSON, with the
+// data in base64, is at most maxSize bytes.
+func chunkDataSize(chunk types.DAChunkStruct, maxSize int) (int, error) {
+	chunk.Data = []byte{}
+	emptyJSON, err := json.Marshal(chunk)
+	if err != nil {
+		return 0, err
+	}
+	// base64 takes 4 bytes for every 3 bytes of data
+	size := (maxSize - len(emptyJSON)) / 4 * 3
+	if size <= 0 {
+		return 0, fmt.Errorf("DA blob limit of %d bytes does not leave room for chunk data", maxSize)
+	}
+	return size, nil
+}
+
+// FetchBatch reads the upload published under key and returns it with its blob,
+// reassembled from its chunks when it was split. The signature of a signed upload is
+// checked; whose key signed it is left to the caller.
+func FetchBatch(ctx context.Context, client DAClient, key string) (*types.DAUploadStruct, []byte, error) {
+	uploadJSON, err := client.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var upload types.DAUploadStruct
+	if err := json.Unmarshal(uploadJSON, &upload); err != nil {
+		return nil, nil, err
+	}
+	if upload.Signature != nil {
+		if err := verifyUpload(upload); err != nil {
+			return nil, nil, err
+		}
+	}
+	if upload.Manifest == nil {
+		return &upload, upload.Blob, nil
+	}
+
+	// no chunk is larger than a submission, so a larger size cannot be allocated for
+	if upload.Manifest.Size < 0 || upload.Manifest.Size > len(upload.Manifest.Chunks)*common.DAMaxBlobSize {
+		return nil, nil, fmt.Errorf("manifest size %d is not that of %d chunks of at most %d bytes", upload.Manifest.Size, len(upload.Manifest.Chunks), common.DAMaxBlobSize)
+	}
+	blob := make([]byte, 0, upload.Manifest.Size)
+	for i, chunkKey := range upload.Manifest.Chunks {
+		chunkJSON, err := client.Get(ctx, chunkKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fetching chunk %d: %w", i, err)
+		}
+
+		var chunk types.DAChunkStruct
+		if err := json.Unmarshal(chunkJSON, &chunk); err != nil {
+			return nil, nil, fmt.Errorf("error decoding chunk %d: %w", i, err)
+		}
+		if chunk.Index != i || chunk.BatchNumber != upload.MetaData.BatchNumber {
+			return nil, nil, fmt.Errorf("chunk %d belongs to batch %d at index %d", i, chunk.BatchNumber, chunk.Index)
+		}
+		blob = append(blob, chunk.Data...)
+	}
+
+	hash := sha256.Sum256(blob)
+	if len(blob) != upload.Manifest.Size || hex.EncodeToString(hash[:]) != upload.Manifest.Hash {
+		return nil, nil, fmt.Errorf("reassembled blob does not match the manifest")
+	}
+
+	return &upload, blob, nil
+}
@@ -0,0 +1,225 @@
+package da_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+// sizeLimitedClient fails any submission larger than limit.
+type sizeLimitedClient struct {
+	*LocalClient
+	limit       int
+	submissions int
+}
+
+func (c *sizeLimitedClient) Submit(ctx context.Context, blob []byte) (string, error) {
+	c.submissions++
+	if len(blob) > c.limit {
+		return "", errors.New("submission above the limit")
+	}
+	return c.LocalClient.Submit(ctx, blob)
+}
+
+func testUpload() types.DAUploadStruct {
+	upload := types.DAUploadStruct{
+		Version:          2,
+		Proof:            json.RawMessage(`{"Ar":"proof"}`),
+		TxnHashes:        []string{"0x01", "0x02"},
+		CurrentStateHash: "ab",
+	}
+	upload.MetaData.BatchNumber = 3
+	return upload
+}
+
+func TestSubmitBatchRoundTrip(t *testing.T) {
+	const limit = 1024
+	random := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name            string
+		blobSize        int
+		wantSubmissions int
+	}{
+		{"inline", 200, 1},
+		// 700 raw bytes fit the limit but not once encoded in base64 with the upload
+		{"base64 overflow", 700, 2},
+		{"many chunks", 5000, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, err := NewLocalClient(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &sizeLimitedClient{LocalClient: local, limit: limit}
+			blob := make([]byte, tt.blobSize)
+			random.Read(blob)
+
+			key, submissions, err := SubmitBatch(context.Background(), client, nil, testUpload(), blob, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if submissions != tt.wantSubmissions || client.submissions != tt.wantSubmissions {
+				t.Fatalf("got %d submissions (%d made), want %d", submissions, client.submissions, tt.wantSubmissions)
+			}
+
+			upload, got, err := FetchBatch(context.Background(), client, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, blob) {
+				t.Fatal("fetched blob differs from the submitted one")
+			}
+			if (upload.Manifest != nil) != (tt.wantSubmissions > 1) {
+				t.Fatalf("unexpected manifest %+v", upload.Manifest)
+			}
+			if upload.MetaData.BatchNumber != 3 || upload.CurrentStateHash != "ab" {
+				t.Fatalf("unexpected upload %+v", upload)
+			}
+		})
+	}
+}
+
+func TestSubmitBatchLimitTooSmall(t *testing.T) {
+	local, err := NewLocalClient(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = SubmitBatch(context.Background(), local, nil, testUpload(), make([]byte, 100), 40)
+	if err == nil || !strings.Contains(err.Error(), "does not leave room") {
+		t.Fatalf("got error %v", err)
+	}
+
+	upload := testUpload()
+	upload.TxnHashes = make([]string, 200)
+	_, _, err = SubmitBatch(context.Background(), local, nil, upload, make([]byte, 100), 400)
+	if err == nil || !strings.Contains(err.Error(), "without its blob") {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestFetchBatchRejectsTamperedChunk(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocalClient(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := bytes.Repeat([]byte("x"), 3000)
+	key, _, err := SubmitBatch(context.Background(), local, nil, testUpload(), blob, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, _, err := FetchBatch(context.Background(), local, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunkJSON, _ := json.Marshal(types.DAChunkStruct{Version: 2, BatchNumber: 3, Index: 0, Data: []byte("y")})
+	if err := os.WriteFile(filepath.Join(dir, upload.Manifest.Chunks[0]), chunkJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := FetchBatch(context.Background(), local, key); err == nil || !strings.Contains(err.Error(), "does not match the manifest") {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestFetchBatchRejectsManifestSize(t *testing.T) {
+	local, err := NewLocalClient(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{-1, 2*common.DAMaxBlobSize + 1, 1 << 50} {
+		upload := testUpload()
+		upload.Manifest = &types.DAManifestStruct{Chunks: []string{"chunk-0", "chunk-1"}, Size: size}
+		uploadJSON, _ := json.Marshal(upload)
+		key, err := local.Submit(context.Background(), uploadJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := FetchBatch(context.Background(), local, key); err == nil || !strings.Contains(err.Error(), "is not that of 2 chunks") {
+			t.Errorf("got error %v for size %d", err, size)
+		}
+	}
+}
+
+func TestSubmitBatchSigned(t *testing.T) {
+	key, err := keyring.Create(t.TempDir(), "operator", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		blobSize int
+		tamper   bool
+		wantErr  string
+	}{
+		{name: "inline", blobSize: 200},
+		{name: "chunked", blobSize: 3000},
+		{name: "tampered", blobSize: 200, tamper: true, wantErr: "invalid signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			local, err := NewLocalClient(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob := bytes.Repeat([]byte("x"), tt.blobSize)
+			daKey, _, err := SubmitBatch(context.Background(), local, key, testUpload(), blob, 1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.tamper {
+				uploadJSON, err := local.Get(context.Background(), daKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tampered := bytes.Replace(uploadJSON, []byte(`"currentStateHash":"ab"`), []byte(`"currentStateHash":"cd"`), 1)
+				if err := os.WriteFile(filepath.Join(dir, daKey), tampered, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			upload, got, err := FetchBatch(context.Background(), local, daKey)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, blob) || !bytes.Equal(upload.PubKey, key.PubKey()) {
+				t.Fatalf("unexpected upload %+v", upload)
+			}
+
+			// the stored upload is signed in the format of settlement requests
+			uploadJSON, _ := local.Get(context.Background(), daKey)
+			var signature struct {
+				PubKey    []byte `json:"pub_key"`
+				Signature []byte `json:"signature"`
+			}
+			json.Unmarshal(uploadJSON, &signature)
+			signBytes, err := keyring.SignBytes(uploadJSON)
+			if err != nil || !keyring.Verify(signature.PubKey, signBytes, signature.Signature) {
+				t.Fatalf("upload is not signed over its sign bytes: %v", err)
+			}
+		})
+	}
+}
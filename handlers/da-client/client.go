@@ -0,0 +1,101 @@
+package da_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+// DAClient publishes batch blobs to a data availability layer and reads them back by the
+// key returned on submission.
+type DAClient interface {
+	// Name is the DA layer recorded with every batch, e.g. "celestia".
+	Name() string
+	// Submit publishes blob and returns the key under which it can be retrieved.
+	Submit(ctx context.Context, blob []byte) (string, error)
+	// Get returns the blob published under key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Status returns an error when the DA layer cannot currently accept blobs.
+	Status(ctx context.Context) error
+}
+
+const (
+	Celestia     = "celestia"
+	CelestiaNode = "celestia-node"
+	Avail        = "avail"
+	EigenDA      = "eigenda"
+	Local        = "local"
+)
+
+// New returns the client of the DA layer named daSelected, as written in the daInfo
+// section of chainInfo.json. Names are case-insensitive. Requests made through the client
+// are recorded in the DA metrics.
+func New(daSelected string) (DAClient, error) {
+	var client DAClient
+	switch strings.ToLower(daSelected) {
+	case Celestia:
+		rpc := os.Getenv(DaClientRPCEnv)
+		if rpc == "" {
+			rpc = common.DaClientRPC
+		}
+		client = NewCelestiaClient(rpc)
+	case CelestiaNode:
+		nodeClient, err := NewCelestiaNodeClient(common.CelestiaNodeRPC, common.CelestiaNamespaceID, os.Getenv(CelestiaAuthTokenEnv))
+		if err != nil {
+			return nil, err
+		}
+		client = nodeClient
+	case Avail:
+		client = NewAvailClient(common.AvailClientRPC, common.AvailAppID)
+	case EigenDA:
+		client = NewEigenDAClient(common.EigenDAClientRPC)
+	case Local:
+		localClient, err := NewLocalClient(common.LocalDADirectory)
+		if err != nil {
+			return nil, err
+		}
+		client = localClient
+	default:
+		return nil, fmt.Errorf("unsupported DA layer %q", daSelected)
+	}
+	return instrumented{client}, nil
+}
+
+// NewFromChainInfo returns the DA layers configured in chainInfoFile: daSelected as the
+// primary and, when set, daSecondary as the layer to fail over to. Uploads are signed with
+// key unless it is nil.
+func NewFromChainInfo(chainInfoFile string, key *keyring.Key) (*Failover, error) {
+	chainInfoBytes, err := os.ReadFile(chainInfoFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", chainInfoFile, err)
+	}
+
+	var chainInfo types.ChainInfoStruct
+	if err := json.Unmarshal(chainInfoBytes, &chainInfo); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", chainInfoFile, err)
+	}
+
+	primary, err := New(chainInfo.DaInfo.DaSelected)
+	if err != nil {
+		return nil, err
+	}
+
+	var secondary DAClient
+	if chainInfo.DaInfo.DaSecondary != "" {
+		secondary, err = New(chainInfo.DaInfo.DaSecondary)
+		if err != nil {
+			return nil, err
+		}
+		if secondary.Name() == primary.Name() {
+			return nil, fmt.Errorf("daSecondary must differ from daSelected")
+		}
+	}
+
+	return NewFailover(primary, secondary, common.DAFailoverAfter, key), nil
+}
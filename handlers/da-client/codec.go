@@ -0,0 +1,91 @@
+package da_client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs of a batch blob. The codec id is written after the version byte.
+const (
+	CodecNone   = "none"
+	CodecGzip   = "gzip"
+	CodecZstd   = "zstd"
+	CodecBrotli = "brotli"
+)
+
+var codecIDs = map[string]byte{
+	CodecNone:   0,
+	CodecGzip:   1,
+	CodecZstd:   2,
+	CodecBrotli: 3,
+}
+
+func codecName(id byte) (string, error) {
+	for name, codecID := range codecIDs {
+		if codecID == id {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown compression codec %d", id)
+}
+
+func compress(codec string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecGzip:
+		w = gzip.NewWriter(&buf)
+	case CodecZstd:
+		zw, err := zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	case CodecBrotli:
+		w = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %q", codec)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case CodecZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case CodecBrotli:
+		return io.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %q", codec)
+	}
+}
@@ -0,0 +1,59 @@
+package da_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EigenDAClient submits blobs through an EigenDA proxy. Keys are the hex encoded
+// certificates returned by the proxy.
+type EigenDAClient struct {
+	rpc    string
+	client *http.Client
+}
+
+func NewEigenDAClient(rpc string) *EigenDAClient {
+	return &EigenDAClient{rpc: strings.TrimRight(rpc, "/"), client: &http.Client{}}
+}
+
+func (c *EigenDAClient) Name() string {
+	return EigenDA
+}
+
+func (c *EigenDAClient) Submit(ctx context.Context, blob []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/put/?commitment_mode=simple", c.rpc), bytes.NewReader(blob))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	commitment, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("eigenda proxy returned status %d: %s", res.StatusCode, bytes.TrimSpace(commitment))
+	}
+
+	return "0x" + hex.EncodeToString(commitment), nil
+}
+
+func (c *EigenDAClient) Get(ctx context.Context, key string) ([]byte, error) {
+	return httpGet(ctx, c.client, fmt.Sprintf("%s/get/%s?commitment_mode=simple", c.rpc, key))
+}
+
+func (c *EigenDAClient) Status(ctx context.Context) error {
+	_, err := httpGet(ctx, c.client, fmt.Sprintf("%s/health", c.rpc))
+	return err
+}
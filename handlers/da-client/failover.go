@@ -0,0 +1,100 @@
+package da_client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+)
+
+// Failover selects between a primary and an optional secondary DA layer. Submissions go to
+// the active layer; after maxFailures consecutive failures the other layer becomes active.
+// Blobs are always read back from the layer recorded for the batch, see Client. Uploads
+// are signed with the operator key, see Key.
+type Failover struct {
+	mu          sync.Mutex
+	key         *keyring.Key
+	primary     DAClient
+	secondary   DAClient
+	active      DAClient
+	failures    int
+	maxFailures int
+}
+
+// NewFailover returns a Failover starting on primary. secondary may be nil, in which case
+// primary stays active whatever its failures. key may be nil to leave uploads unsigned.
+func NewFailover(primary DAClient, secondary DAClient, maxFailures int, key *keyring.Key) *Failover {
+	return &Failover{key: key, primary: primary, secondary: secondary, active: primary, maxFailures: maxFailures}
+}
+
+// Key returns the operator key uploads are signed with, or nil when they are not signed.
+func (f *Failover) Key() *keyring.Key {
+	return f.key
+}
+
+// Active returns the DA layer new batches are submitted to.
+func (f *Failover) Active() DAClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.active
+}
+
+// ReportSuccess resets the failure count of client.
+func (f *Failover) ReportSuccess(client DAClient) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if client == f.active {
+		f.failures = 0
+	}
+}
+
+// ReportFailure counts a failed submission to client and fails over to the other layer
+// once maxFailures are reached.
+func (f *Failover) ReportFailure(client DAClient) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if client != f.active || f.secondary == nil {
+		return
+	}
+
+	f.failures++
+	if f.failures < f.maxFailures {
+		return
+	}
+
+	next := f.secondary
+	if f.active == f.secondary {
+		next = f.primary
+	}
+	logs.Log.Warn(fmt.Sprintf("%s failed %d times in a row, failing over to %s", f.active.Name(), f.failures, next.Name()))
+	f.active = next
+	f.failures = 0
+}
+
+// Attempts returns the number of consecutive failed submissions after which every
+// configured layer has failed maxFailures times.
+func (f *Failover) Attempts() int {
+	attempts := f.maxFailures
+	if attempts < 1 {
+		attempts = 1
+	}
+	if f.secondary != nil {
+		attempts *= 2
+	}
+	return attempts
+}
+
+// Client returns the configured DA layer called name, as recorded in DAStruct.DAClientName.
+func (f *Failover) Client(name string) (DAClient, error) {
+	for _, client := range []DAClient{f.primary, f.secondary} {
+		if client != nil && strings.EqualFold(client.Name(), name) {
+			return client, nil
+		}
+	}
+	return nil, fmt.Errorf("DA layer %q is not configured", name)
+}
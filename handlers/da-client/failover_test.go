@@ -0,0 +1,65 @@
+package da_client
+
+import (
+	"context"
+	"testing"
+)
+
+// namedClient is a DAClient that only has a name.
+type namedClient string
+
+func (c namedClient) Name() string                                   { return string(c) }
+func (c namedClient) Submit(context.Context, []byte) (string, error) { return "", nil }
+func (c namedClient) Get(context.Context, string) ([]byte, error)    { return nil, nil }
+func (c namedClient) Status(context.Context) error                   { return nil }
+
+func TestFailover(t *testing.T) {
+	primary, secondary := namedClient("primary"), namedClient("secondary")
+
+	tests := []struct {
+		name         string
+		secondary    DAClient
+		maxFailures  int
+		failures     int
+		wantActive   DAClient
+		wantAttempts int
+	}{
+		{"no secondary", nil, 3, 10, primary, 3},
+		{"below the limit", secondary, 3, 2, primary, 6},
+		{"failed over", secondary, 3, 3, secondary, 6},
+		{"failed back", secondary, 3, 6, primary, 6},
+		{"zero limit", nil, 0, 1, primary, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFailover(primary, tt.secondary, tt.maxFailures, nil)
+			for i := 0; i < tt.failures; i++ {
+				f.ReportFailure(f.Active())
+			}
+			if f.Active() != tt.wantActive {
+				t.Fatalf("active is %s, want %s", f.Active().Name(), tt.wantActive.Name())
+			}
+			if f.Attempts() != tt.wantAttempts {
+				t.Fatalf("got %d attempts, want %d", f.Attempts(), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestFailoverSuccessResetsFailures(t *testing.T) {
+	primary, secondary := namedClient("primary"), namedClient("secondary")
+	f := NewFailover(primary, secondary, 2, nil)
+
+	f.ReportFailure(primary)
+	f.ReportSuccess(primary)
+	f.ReportFailure(primary)
+	if f.Active() != primary {
+		t.Fatal("failed over although a success reset the failures")
+	}
+	// failures of a layer that is no longer active do not count
+	f.ReportFailure(secondary)
+	f.ReportFailure(primary)
+	if f.Active() != secondary {
+		t.Fatal("did not fail over after two failures in a row")
+	}
+}
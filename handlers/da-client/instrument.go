@@ -0,0 +1,41 @@
+package da_client
+
+import (
+	"context"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/metrics"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// instrumented records the latency and errors of every request to the DA layer it wraps,
+// and traces the requests made for a batch.
+type instrumented struct {
+	DAClient
+}
+
+func (c instrumented) Submit(ctx context.Context, blob []byte) (string, error) {
+	ctx, span := tracing.Start(ctx, "da.submit", attribute.String("da.layer", c.Name()), attribute.Int("da.blob_size", len(blob)))
+	start := time.Now()
+	key, err := c.DAClient.Submit(ctx, blob)
+	metrics.ObserveDA(c.Name(), "submit", start, err)
+	tracing.End(span, err)
+	return key, err
+}
+
+func (c instrumented) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "da.get", attribute.String("da.layer", c.Name()))
+	start := time.Now()
+	blob, err := c.DAClient.Get(ctx, key)
+	metrics.ObserveDA(c.Name(), "get", start, err)
+	tracing.End(span, err)
+	return blob, err
+}
+
+func (c instrumented) Status(ctx context.Context) error {
+	start := time.Now()
+	err := c.DAClient.Status(ctx)
+	metrics.ObserveDA(c.Name(), "status", start, err)
+	return err
+}
@@ -0,0 +1,50 @@
+package da_client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalClient stores blobs as files in a directory, keyed by their sha256. It has no
+// availability guarantees and is meant for development only.
+type LocalClient struct {
+	dir string
+}
+
+func NewLocalClient(dir string) (*LocalClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating local DA directory: %w", err)
+	}
+	return &LocalClient{dir: dir}, nil
+}
+
+func (c *LocalClient) Name() string {
+	return Local
+}
+
+func (c *LocalClient) Submit(ctx context.Context, blob []byte) (string, error) {
+	hash := sha256.Sum256(blob)
+	key := hex.EncodeToString(hash[:])
+
+	if err := os.WriteFile(filepath.Join(c.dir, key), blob, 0644); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (c *LocalClient) Get(ctx context.Context, key string) ([]byte, error) {
+	if filepath.Base(key) != key {
+		return nil, fmt.Errorf("invalid local DA key %q", key)
+	}
+	return os.ReadFile(filepath.Join(c.dir, key))
+}
+
+func (c *LocalClient) Status(ctx context.Context) error {
+	_, err := os.Stat(c.dir)
+	return err
+}
@@ -0,0 +1,44 @@
+package da_client
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+// signUpload fills the public key and signature of upload with key. The signature covers
+// keyring.SignBytes of the upload, as for settlement requests.
+func signUpload(key *keyring.Key, upload *types.DAUploadStruct) error {
+	upload.PubKey = key.PubKey()
+	upload.Signature = nil
+	uploadJSON, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	signBytes, err := keyring.SignBytes(uploadJSON)
+	if err != nil {
+		return err
+	}
+
+	upload.Signature, err = key.Sign(signBytes)
+	return err
+}
+
+// verifyUpload checks the signature of an upload signed by signUpload.
+func verifyUpload(upload types.DAUploadStruct) error {
+	uploadJSON, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	signBytes, err := keyring.SignBytes(uploadJSON)
+	if err != nil {
+		return err
+	}
+
+	if !keyring.Verify(upload.PubKey, signBytes, upload.Signature) {
+		return errors.New("invalid signature on batch upload")
+	}
+	return nil
+}
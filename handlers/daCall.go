@@ -1,62 +1,91 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
+	"time"
+
 	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
 	"github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	da_client "github.com/airchains-network/evm-sequencer-node/handlers/da-client"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
 	"github.com/airchains-network/evm-sequencer-node/types"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/syndtr/goleveldb/leveldb"
-	"net/http"
-	"os"
-	"strconv"
-	"time"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func DaCall(transactions []string, ethClient *ethclient.Client, ctx context.Context, currentStateHash string, batchNumber int, ldda *leveldb.DB) (string, error) {
-	logs.Log.Warn("DA Calling")
+func DaCall(transactions []string, ethClient *ethclient.Client, ctx context.Context, currentStateHash string, batchNumber int, ldda *leveldb.DB, daLayers *da_client.Failover) (string, error) {
+	logger := logs.FromContext(ctx)
+	logger.Warn("DA Calling")
 	proofGet, proofGetErr := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_%d", batchNumber)), nil)
 	if proofGetErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting proof from db : %s", proofGetErr.Error()))
+		logger.Error(fmt.Sprintf("Error in getting proof from db : %s", proofGetErr.Error()))
 		os.Exit(0)
 	}
 
-	var proofDecode types.ProofStruct
-
-	proofDecodeErr := json.Unmarshal(proofGet, &proofDecode)
-	if proofDecodeErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in unmarshalling proof : %s", proofDecodeErr.Error()))
+	if !json.Valid(proofGet) {
+		logger.Error(fmt.Sprintf("Error in unmarshalling proof : invalid proof json for batch %d", batchNumber))
 		os.Exit(0)
 	}
 
 	daGet, daGetErr := ldda.Get([]byte(fmt.Sprintf("batch_%d", batchNumber-1)), nil)
 	if daGetErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting da from db : %s", daGetErr.Error()))
+		logger.Error(fmt.Sprintf("Error in getting da from db : %s", daGetErr.Error()))
 		os.Exit(0)
 	}
 
 	var daDecode types.DAStruct
 	daDecodeErr := json.Unmarshal(daGet, &daDecode)
 	if daDecodeErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in unmarshalling da : %s", daDecodeErr.Error()))
+		logger.Error(fmt.Sprintf("Error in unmarshalling da : %s", daDecodeErr.Error()))
 		os.Exit(0)
 	}
 
 	chainID, err := ethClient.NetworkID(ctx)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to get the network ID: %v", err))
+		logger.Error(fmt.Sprintf("Failed to get the network ID: %v", err))
 		os.Exit(0)
 	}
 
+	_, span := tracing.Start(ctx, "build-blob", attribute.Int("batch.transactions", len(transactions)))
+	batchBlob, err := buildBatchBlob(ctx, ethClient, transactions)
+	if err != nil {
+		tracing.End(span, err)
+		logger.Error(fmt.Sprintf("Error in collecting transactions of batch %d : %s", batchNumber, err.Error()))
+		return "", err
+	}
+	batchBlob.ChainID = chainID.String()
+	batchBlob.BatchNumber = uint64(batchNumber)
+	batchBlob.PreviousStateHash = daDecode.CurrentStateHash
+	batchBlob.CurrentStateHash = currentStateHash
+
+	blob, blobSize, err := da_client.EncodeBatchBlob(batchBlob, common.DACompression)
+	span.SetAttributes(attribute.Int("blob.size", blobSize), attribute.Int("blob.compressed_size", len(blob)))
+	tracing.End(span, err)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in encoding batch blob : %s", err.Error()))
+		return "", err
+	}
+
 	DaStruct := types.DAUploadStruct{
-		Proof:             proofDecode,
+		Version:           da_client.BlobVersion,
+		Proof:             proofGet,
+		ProofBackend:      prover.ProofBackend(batchNumber),
+		ProofCurve:        prover.ProofCurve(batchNumber),
 		TxnHashes:         transactions,
 		CurrentStateHash:  currentStateHash,
-		PreviousStateHash: daDecode.PreviousStateHash,
+		PreviousStateHash: daDecode.CurrentStateHash,
 		MetaData: struct {
 			ChainID     string `json:"chainID"`
 			BatchNumber int    `json:"batchNumber"`
@@ -66,69 +95,101 @@ func DaCall(transactions []string, ethClient *ethclient.Client, ctx context.Cont
 		},
 	}
 
-	payloadJSON, payloadJSONErr := json.Marshal(DaStruct)
-
-	if payloadJSONErr != nil {
-		fmt.Println("Error in marshalling payload")
-		return "", payloadJSONErr
-		//logs.LogMessage("ERROR:", fmt.Sprintf("Failed to read file: %s"+payloadJSONErr.Error()))
-		//os.Exit(0)
-	}
-
-	client := &http.Client{}
-
-	req, reqErr := http.NewRequest("POST", common.DaClientRPC, bytes.NewBuffer(payloadJSON))
-	if reqErr != nil {
-		return "", reqErr
-		//logs.LogMessage("ERROR:", fmt.Sprintf("Resquesting in DA RPC : %s"+reqErr.Error()))
-		//os.Exit(0)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	res, resErr := client.Do(req)
-	if resErr != nil {
-		fmt.Println("Error in requesting")
-		return "", resErr
-		//logs.LogMessage("ERROR:", fmt.Sprintf("Resquesting in DA RPC : %s"+resErr.Error()))
-		//os.Exit(0)
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != 200 {
-		logs.Log.Error(fmt.Sprintf("DA RPC is not responding, retrying in 1 second"))
-		time.Sleep(3 * time.Second)
-		_, _ = DaCall(transactions, ethClient, ctx, currentStateHash, batchNumber, ldda)
-	}
-
-	var response types.DAResponseStruct
-	decodeErr := json.NewDecoder(res.Body).Decode(&response)
-	if decodeErr != nil {
-		fmt.Println("Error in decoding")
-		return "", decodeErr
-	}
-
-	if response.DaKeyHash == "nil" {
-		logs.Log.Error(fmt.Sprintf("DA RPC is not responding, retrying in 1 second"))
-		time.Sleep(3 * time.Second)
-		_, _ = DaCall(transactions, ethClient, ctx, currentStateHash, batchNumber, ldda)
+	blobHash := sha256.Sum256(blob)
+
+	var daClient da_client.DAClient
+	var daKeyHash string
+	var chunks int
+	for attempt := 1; ; attempt++ {
+		daClient = daLayers.Active()
+		daKeyHash, chunks, err = da_client.SubmitBatch(ctx, daClient, daLayers.Key(), DaStruct, blob, common.DAMaxBlobSize)
+		if err == nil {
+			daLayers.ReportSuccess(daClient)
+			break
+		}
+		daLayers.ReportFailure(daClient)
+		if attempt >= daLayers.Attempts() {
+			logger.Error(fmt.Sprintf("Error in submitting batch to %s : %s, every DA layer failed", daClient.Name(), err.Error()))
+			return "", fmt.Errorf("every DA layer failed, last error from %s: %w", daClient.Name(), err)
+		}
+		logger.Error(fmt.Sprintf("Error in submitting batch to %s : %s, retrying in 3 seconds", daClient.Name(), err.Error()))
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(3 * time.Second):
+		}
 	}
 
 	da := types.DAStruct{
-		DAKey:             response.DaKeyHash,
-		DAClientName:      "celestia",
+		DAKey:             daKeyHash,
+		DAClientName:      daClient.Name(),
 		BatchNumber:       strconv.Itoa(batchNumber),
 		PreviousStateHash: daDecode.CurrentStateHash,
 		CurrentStateHash:  currentStateHash,
+		Codec:             common.DACompression,
+		BlobSize:          blobSize,
+		CompressedSize:    len(blob),
+		CompressionRatio:  float64(blobSize) / float64(len(blob)),
+		Chunks:            chunks,
+		BlobHash:          hex.EncodeToString(blobHash[:]),
 	}
 
 	daBytes, err := json.Marshal(da)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in marshalling da : %s", err.Error()))
+		return "", err
+	}
 
 	batchKey := fmt.Sprintf("batch_%d", batchNumber)
 	err = ldda.Put([]byte(batchKey), daBytes, nil)
 	if err != nil {
-		fmt.Println("Error in putting da in db")
+		logger.Error(fmt.Sprintf("Error in putting da in db : %s", err.Error()))
 		return "", err
 	}
+	events.Publish(events.TypeBatchDAPosted, events.BatchDAPosted{Batch: batchNumber, DALayer: daClient.Name(), DAKey: daKeyHash, Chunks: chunks})
+
+	return daKeyHash, nil
+}
+
+// buildBatchBlob fetches the canonical encoding of every transaction of the batch and the
+// RLP encoded headers of the blocks they belong to.
+func buildBatchBlob(ctx context.Context, ethClient *ethclient.Client, transactions []string) (*types.DABatchBlob, error) {
+	batchBlob := &types.DABatchBlob{}
+	lastBlock := int64(-1)
+
+	for _, transactionHash := range transactions {
+		txHash := ethcommon.HexToHash(transactionHash)
+
+		tx, _, err := ethClient.TransactionByHash(ctx, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", transactionHash, err)
+		}
+		txBytes, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		batchBlob.Transactions = append(batchBlob.Transactions, txBytes)
+
+		receipt, err := ethClient.TransactionReceipt(ctx, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("receipt of %s: %w", transactionHash, err)
+		}
+
+		// transactions are in block order, so each header is added once
+		if receipt.BlockNumber.Int64() == lastBlock {
+			continue
+		}
+		header, err := ethClient.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("header of block %s: %w", receipt.BlockNumber, err)
+		}
+		headerBytes, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return nil, err
+		}
+		batchBlob.Headers = append(batchBlob.Headers, headerBytes)
+		lastBlock = receipt.BlockNumber.Int64()
+	}
 
-	return response.DaKeyHash, nil
+	return batchBlob, nil
 }
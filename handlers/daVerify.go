@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	da_client "github.com/airchains-network/evm-sequencer-node/handlers/da-client"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// VerifyDAPublication reads the blob of batchNumber back from the DA layer and key stored
+// in ldda, checks that it matches the batch in ldbatch and the proof in the proof
+// store, and marks the batch as DA-confirmed.
+func VerifyDAPublication(ctx context.Context, daLayers *da_client.Failover, ldbatch *leveldb.DB, ldda *leveldb.DB, batchNumber int) error {
+	daKey := []byte(fmt.Sprintf("batch_%d", batchNumber))
+	daBytes, err := ldda.Get(daKey, nil)
+	if err != nil {
+		return fmt.Errorf("error getting da of batch %d: %w", batchNumber, err)
+	}
+	var da types.DAStruct
+	if err := json.Unmarshal(daBytes, &da); err != nil {
+		return fmt.Errorf("error decoding da of batch %d: %w", batchNumber, err)
+	}
+
+	batchBytes, err := ldbatch.Get([]byte(fmt.Sprintf("batch-%d", batchNumber)), nil)
+	if err != nil {
+		return fmt.Errorf("error getting batch %d: %w", batchNumber, err)
+	}
+	var batch types.BatchStruct
+	if err := json.Unmarshal(batchBytes, &batch); err != nil {
+		return fmt.Errorf("error decoding batch %d: %w", batchNumber, err)
+	}
+
+	proof, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_%d", batchNumber)), nil)
+	if err != nil {
+		return fmt.Errorf("error getting proof of batch %d: %w", batchNumber, err)
+	}
+
+	daClient, err := daLayers.Client(da.DAClientName)
+	if err != nil {
+		return err
+	}
+	upload, uploadBlob, err := da_client.FetchBatch(ctx, daClient, da.DAKey)
+	if err != nil {
+		return fmt.Errorf("error fetching batch %d from %s: %w", batchNumber, daClient.Name(), err)
+	}
+
+	if key := daLayers.Key(); key != nil && !bytes.Equal(upload.PubKey, key.PubKey()) {
+		return fmt.Errorf("DA blob of batch %d is not signed by the operator key", batchNumber)
+	}
+	if upload.MetaData.BatchNumber != batchNumber {
+		return fmt.Errorf("DA blob is for batch %d, expected %d", upload.MetaData.BatchNumber, batchNumber)
+	}
+	if upload.PreviousStateHash != da.PreviousStateHash || upload.CurrentStateHash != da.CurrentStateHash {
+		return fmt.Errorf("state hashes on DA do not match batch %d", batchNumber)
+	}
+	if !sameJSON(upload.Proof, proof) {
+		return fmt.Errorf("proof on DA does not match the proof of batch %d", batchNumber)
+	}
+
+	blob, err := da_client.DecodeBatchBlob(uploadBlob)
+	if err != nil {
+		return fmt.Errorf("error decoding blob of batch %d: %w", batchNumber, err)
+	}
+	if blob.BatchNumber != uint64(batchNumber) || blob.PreviousStateHash != da.PreviousStateHash || blob.CurrentStateHash != da.CurrentStateHash {
+		return fmt.Errorf("blob header on DA does not match batch %d", batchNumber)
+	}
+	if len(blob.Transactions) != len(batch.TransactionHash) {
+		return fmt.Errorf("DA blob has %d transactions, batch %d has %d", len(blob.Transactions), batchNumber, len(batch.TransactionHash))
+	}
+	for i, txBytes := range blob.Transactions {
+		var tx ethtypes.Transaction
+		if err := tx.UnmarshalBinary(txBytes); err != nil {
+			return fmt.Errorf("error decoding transaction %d of batch %d: %w", i, batchNumber, err)
+		}
+		if !strings.EqualFold(tx.Hash().Hex(), batch.TransactionHash[i]) {
+			return fmt.Errorf("transaction %d on DA is %s, batch %d has %s", i, tx.Hash().Hex(), batchNumber, batch.TransactionHash[i])
+		}
+	}
+
+	da.DAConfirmed = true
+	daBytes, err = json.Marshal(da)
+	if err != nil {
+		return err
+	}
+
+	return ldda.Put(daKey, daBytes, nil)
+}
+
+// sameJSON reports whether a and b are the same JSON document up to insignificant space.
+func sameJSON(a, b []byte) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return false
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
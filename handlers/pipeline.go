@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	da_client "github.com/airchains-network/evm-sequencer-node/handlers/da-client"
+	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/syndtr/goleveldb/leveldb"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Stages of the batch lifecycle. Each stage is run by its own worker taking batches in
+// order, so batch N+1 is proved and posted to DA while batch N is being settled.
+const (
+	StageCollected = "collected"
+	StageProved    = "proved"
+	StageDAPosted  = "da-posted"
+	StageSubmitted = "submitted"
+	StageVerified  = "verified"
+	StageFailed    = "failed"
+)
+
+var stageOrder = map[string]int{
+	StageCollected: 1,
+	StageProved:    2,
+	StageDAPosted:  3,
+	StageSubmitted: 4,
+	StageVerified:  5,
+}
+
+// stageReached reports whether a batch in stage has completed target.
+func stageReached(stage string, target string) bool {
+	return stage != StageFailed && stageOrder[stage] >= stageOrder[target]
+}
+
+// GetBatchState returns the lifecycle stage of batchNumber.
+func GetBatchState(ldbatch *leveldb.DB, batchNumber int) (*types.BatchStateStruct, error) {
+	stateBytes, err := ldbatch.Get([]byte(fmt.Sprintf("batch-state-%d", batchNumber)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var state types.BatchStateStruct
+	if err := json.Unmarshal(stateBytes, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func putBatchState(ldbatch *leveldb.DB, state *types.BatchStateStruct) error {
+	state.UpdatedAt = time.Now().Unix()
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ldbatch.Put([]byte(fmt.Sprintf("batch-state-%d", state.BatchNumber)), stateBytes, nil)
+}
+
+// advanceBatch persists state in stage, stopping the sequencer if it cannot be saved.
+func advanceBatch(ldbatch *leveldb.DB, state *types.BatchStateStruct, stage string) {
+	state.Stage = stage
+	if err := putBatchState(ldbatch, state); err != nil {
+		logs.Log.With("batch", state.BatchNumber).Error(fmt.Sprintf("Error in saving state of batch %d : %s", state.BatchNumber, err.Error()))
+		os.Exit(0)
+	}
+	metrics.BatchStage.WithLabelValues(stage).Set(float64(state.BatchNumber))
+	if stage == StageVerified {
+		tracing.EndBatch(state.BatchNumber, nil)
+	}
+}
+
+// failBatch records that batchNumber failed at stage. When Control parks failed batches the
+// batch stays failed, and the workers wait on it, until it is retried or failed through
+// Control. Otherwise the sequencer stops, and the batch is resumed on the next start.
+func failBatch(ldbatch *leveldb.DB, batchNumber int, stage string, failure error) {
+	saveBatchFailure(ldbatch, batchNumber, stage, failure)
+
+	state, err := GetBatchState(ldbatch, batchNumber)
+	if err != nil {
+		state = &types.BatchStateStruct{BatchNumber: batchNumber}
+	}
+	if state.Stage != StageFailed {
+		state.ResumeStage = state.Stage
+	}
+	state.FailedStage = stage
+	state.Error = failure.Error()
+	advanceBatch(ldbatch, state, StageFailed)
+	events.Publish(events.TypeBatchFailed, events.BatchFailed{Batch: batchNumber, Stage: stage, Error: failure.Error()})
+	tracing.EndBatch(batchNumber, fmt.Errorf("%s: %w", stage, failure))
+	if Control.ParksFailedBatches() {
+		logs.Log.With("batch", batchNumber).Error(fmt.Sprintf("Batch %d failed at %s, waiting for it to be retried or failed through the admin API", batchNumber, stage))
+		return
+	}
+	tracing.Flush(context.Background())
+	flushCtx, cancel := context.WithTimeout(context.Background(), common.WebhookTimeout*time.Second)
+	events.Flush(flushCtx)
+	cancel()
+	os.Exit(0)
+}
+
+// waitForStage blocks until batchNumber has completed stage and returns its state. It
+// returns false once stop is closed. A failed batch is waited on until Control resolves it.
+func waitForStage(stop <-chan struct{}, ldbatch *leveldb.DB, batchNumber int, stage string) (*types.BatchStateStruct, bool) {
+	for {
+		select {
+		case <-stop:
+			return nil, false
+		default:
+		}
+
+		state, err := GetBatchState(ldbatch, batchNumber)
+		if err == nil {
+			if stageReached(state.Stage, stage) {
+				return state, true
+			}
+		} else if err != leveldb.ErrNotFound {
+			logs.Log.With("batch", batchNumber).Error(fmt.Sprintf("Error in getting state of batch %d : %s", batchNumber, err.Error()))
+			os.Exit(0)
+		}
+
+		select {
+		case <-stop:
+			return nil, false
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+// restartFailedBatches puts batches that failed before the last run stopped back in the
+// last stage they completed, so only the stage that failed is run again. It returns the
+// number of the first batch that has not been collected.
+func restartFailedBatches(ldbatch *leveldb.DB, firstBatch int) int {
+	batchNumber := firstBatch
+	for ; ; batchNumber++ {
+		state, err := GetBatchState(ldbatch, batchNumber)
+		if err != nil {
+			return batchNumber
+		}
+		if state.Stage == StageFailed {
+			resumeStage := state.ResumeStage
+			if resumeStage == "" {
+				resumeStage = StageCollected
+			}
+			logs.Log.With("batch", batchNumber).Warn(fmt.Sprintf("Batch %d failed at %s in the last run, resuming it after %s", batchNumber, state.FailedStage, resumeStage))
+			state.FailedStage = ""
+			state.ResumeStage = ""
+			state.Error = ""
+			advanceBatch(ldbatch, state, resumeStage)
+		}
+	}
+}
+
+// previousStateHash returns the state root batchNumber starts from, the state hash of the
+// batch before it. That batch is proved but may not be posted to the DA layer yet, so its
+// batch state is read first and its DA record only for batches from before batch states.
+func previousStateHash(ldbatch *leveldb.DB, ldda *leveldb.DB, batchNumber int) (string, error) {
+	if state, err := GetBatchState(ldbatch, batchNumber-1); err == nil && state.CurrentStateHash != "" {
+		return state.CurrentStateHash, nil
+	}
+	da, err := getDa(ldda, batchNumber-1)
+	if err != nil {
+		return "", fmt.Errorf("error getting state hash of batch %d: %w", batchNumber-1, err)
+	}
+	return da.CurrentStateHash, nil
+}
+
+// proveBatches proves every collected batch from firstBatch on until stop is closed or a
+// batch fails.
+func proveBatches(ctx context.Context, stop <-chan struct{}, ldbatch *leveldb.DB, ldda *leveldb.DB, batchProver prover.Prover, firstBatch int) {
+	for batchNumber := firstBatch; ; batchNumber++ {
+		logger := logs.Log.With("batch", batchNumber)
+
+		state, ok := waitForStage(stop, ldbatch, batchNumber, StageCollected)
+		if !ok {
+			return
+		}
+		if stageReached(state.Stage, StageProved) {
+			continue
+		}
+		batchCtx := logs.NewContext(tracing.BatchContext(ctx, batchNumber), logger)
+
+		batch, err := getBatch(ldbatch, batchNumber)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in getting batch %d : %s", batchNumber, err.Error()))
+			os.Exit(0)
+		}
+
+		previousHash, err := previousStateHash(ldbatch, ldda, batchNumber)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(0)
+		}
+
+		// a proof stored before the last run stopped is used if it is for this batch
+		storedResult, err := prover.StoredProofResult(batchNumber)
+		if err == nil && storedResult.PreviousStateHash == previousHash && storedResult.CurrentStateHash == prover.BatchStateHash(*batch) && prover.VerifyBatchProof(batchNumber) == nil {
+			logger.Warn(fmt.Sprintf("Resuming Batch %d from its stored proof", batchNumber))
+			state.CurrentStateHash = storedResult.CurrentStateHash
+			advanceBatch(ldbatch, state, StageProved)
+			events.Publish(events.TypeBatchProved, events.BatchProved{Batch: batchNumber, CurrentStateHash: state.CurrentStateHash})
+			continue
+		}
+
+		provingStart := time.Now()
+		proveCtx, span := tracing.Start(batchCtx, "prove", attribute.Int("batch.transactions", len(batch.TransactionHash)))
+		proofResult, err := batchProver.Prove(proveCtx, *batch, batchNumber, previousHash)
+		tracing.End(span, err)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in generating proof : %s", err.Error()))
+			failBatch(ldbatch, batchNumber, "proving", err)
+			return
+		}
+		metrics.ProvingDuration.Observe(time.Since(provingStart).Seconds())
+
+		_, span = tracing.Start(batchCtx, "verify-proof")
+		err = prover.VerifyBatchProof(batchNumber)
+		tracing.End(span, err)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Proof of batch %d failed local verification, refusing to submit : %s", batchNumber, err.Error()))
+			failBatch(ldbatch, batchNumber, "proof-verification", err)
+			return
+		}
+
+		state.CurrentStateHash = proofResult.CurrentStateHash
+		advanceBatch(ldbatch, state, StageProved)
+		events.Publish(events.TypeBatchProved, events.BatchProved{Batch: batchNumber, CurrentStateHash: state.CurrentStateHash})
+		logger.Warn(fmt.Sprintf("Successfully generated proof for Batch %d", batchNumber))
+	}
+}
+
+// postBatches publishes every proved batch from firstBatch on to the DA layer and reads it
+// back before it is handed to settlement, until stop is closed or a batch fails.
+func postBatches(ctx context.Context, stop <-chan struct{}, client *ethclient.Client, ldbatch *leveldb.DB, ldda *leveldb.DB, daLayers *da_client.Failover, firstBatch int) {
+	for batchNumber := firstBatch; ; batchNumber++ {
+		logger := logs.Log.With("batch", batchNumber)
+
+		state, ok := waitForStage(stop, ldbatch, batchNumber, StageProved)
+		if !ok {
+			return
+		}
+		if stageReached(state.Stage, StageDAPosted) {
+			continue
+		}
+		batchCtx := logs.NewContext(tracing.BatchContext(ctx, batchNumber), logger)
+
+		batch, err := getBatch(ldbatch, batchNumber)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in getting batch %d : %s", batchNumber, err.Error()))
+			os.Exit(0)
+		}
+
+		var daKeyHash string
+		if da, err := getDa(ldda, batchNumber); err == nil && da.DAKey != "" && da.CurrentStateHash == state.CurrentStateHash {
+			// posted before the last run stopped, only the read back is left
+			daKeyHash = da.DAKey
+			logger.Warn(fmt.Sprintf("Resuming Batch %d from its DA key %s", batchNumber, daKeyHash))
+		}
+		for daKeyHash == "" {
+			postCtx, span := tracing.Start(batchCtx, "post-da")
+			daKeyHash, err = DaCall(batch.TransactionHash, client, postCtx, state.CurrentStateHash, batchNumber, ldda, daLayers)
+			tracing.End(span, err)
+			if err == nil {
+				break
+			}
+			logger.Error(fmt.Sprintf("Error in adding Da client : %s", err.Error()))
+			logger.Warn("Trying again...")
+			select {
+			case <-stop:
+				return
+			case <-time.After(3 * time.Second):
+			}
+		}
+
+		logger.Warn(fmt.Sprintf("Successfully added Da client for Batch %s in the latest phase", daKeyHash))
+
+		for attempt := 1; ; attempt++ {
+			verifyCtx, span := tracing.Start(batchCtx, "verify-da", attribute.Int("attempt", attempt))
+			err = VerifyDAPublication(verifyCtx, daLayers, ldbatch, ldda, batchNumber)
+			tracing.End(span, err)
+			if err == nil {
+				break
+			}
+			if attempt > common.DAVerifyRetries {
+				logger.Error(fmt.Sprintf("Batch %d could not be confirmed on DA : %s", batchNumber, err.Error()))
+				failBatch(ldbatch, batchNumber, "da-verification", err)
+				return
+			}
+			logger.Warn(fmt.Sprintf("Batch %d is not confirmed on DA : %s, posting it again", batchNumber, err.Error()))
+			postCtx, span := tracing.Start(batchCtx, "post-da")
+			daKeyHash, err = DaCall(batch.TransactionHash, client, postCtx, state.CurrentStateHash, batchNumber, ldda, daLayers)
+			tracing.End(span, err)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Error in adding Da client : %s", err.Error()))
+				failBatch(ldbatch, batchNumber, "da-submission", err)
+				return
+			}
+		}
+
+		state.DAKey = daKeyHash
+		advanceBatch(ldbatch, state, StageDAPosted)
+	}
+}
+
+// settleBatches submits every batch posted to DA from firstBatch on to the settlement
+// layer, in batch order, and records it as done once it is verified. With an aggregator,
+// batches are submitted as one pod range when the last batch of their range is posted.
+// It returns once stop is closed or a batch fails.
+func settleBatches(ctx context.Context, stop <-chan struct{}, lds *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, aggregator *prover.Aggregator, settlement settlement_client.SettlementClient, firstBatch int) {
+	for batchNumber := firstBatch; ; batchNumber++ {
+		logger := logs.Log.With("batch", batchNumber)
+
+		state, ok := waitForStage(stop, ldbatch, batchNumber, StageDAPosted)
+		if !ok {
+			return
+		}
+		if stageReached(state.Stage, StageVerified) {
+			continue
+		}
+
+		if aggregator != nil {
+			if !aggregator.EndsRange(batchNumber) {
+				continue
+			}
+
+			batchCtx := tracing.BatchContext(ctx, batchNumber)
+
+			firstInRange := batchNumber + 1 - aggregator.Size()
+			_, span := tracing.Start(batchCtx, "aggregate", attribute.Int("range.first", firstInRange))
+			aggregation, err := aggregator.Aggregate(firstInRange)
+			tracing.End(span, err)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Error in aggregating batch proofs : %s", err.Error()))
+				failBatch(ldbatch, batchNumber, "aggregation", err)
+				return
+			}
+
+			rangeCtx, span := tracing.Start(batchCtx, "add-pod-range", attribute.Int("range.first", firstInRange))
+			if !settlement.AddPodRange(rangeCtx, aggregation, lds) {
+				err := fmt.Errorf("pod range %d to %d was rejected", firstInRange, batchNumber)
+				tracing.End(span, err)
+				logger.Error("Error in adding pod range to settlement client")
+				failBatch(ldbatch, batchNumber, "settlement-range", err)
+				return
+			}
+			tracing.End(span, nil)
+
+			for rangeBatch := firstInRange; rangeBatch <= batchNumber; rangeBatch++ {
+				rangeState, err := GetBatchState(ldbatch, rangeBatch)
+				if err != nil {
+					logger.Error(fmt.Sprintf("Error in getting state of batch %d : %s", rangeBatch, err.Error()))
+					os.Exit(0)
+				}
+				advanceBatch(ldbatch, rangeState, StageVerified)
+			}
+		} else {
+			batchCtx := tracing.BatchContext(ctx, batchNumber)
+
+			if !stageReached(state.Stage, StageSubmitted) {
+				witnessVector, err := prover.WitnessVector(batchNumber)
+				if err != nil {
+					logger.Error(fmt.Sprintf("Error in getting witness vector of batch %d : %s", batchNumber, err.Error()))
+					os.Exit(0)
+				}
+
+				currentTime := uint64(time.Now().Unix())
+				addCtx, span := tracing.Start(batchCtx, "add-pod")
+				addBatchRes := settlement.AddBatch(addCtx, witnessVector, batchNumber, state.CurrentStateHash, currentTime, lds, ldda)
+				if addBatchRes == "nil" {
+					err := fmt.Errorf("pod %d was rejected", batchNumber)
+					tracing.End(span, err)
+					logger.Error(fmt.Sprintf("Error in adding batch to settlement client : %s", addBatchRes))
+					failBatch(ldbatch, batchNumber, "settlement-add", err)
+					return
+				}
+				tracing.End(span, nil)
+				state.SettlementResponse = addBatchRes
+				advanceBatch(ldbatch, state, StageSubmitted)
+			}
+
+			proof, err := storedProof(batchNumber)
+			if err != nil {
+				logger.Error(err.Error())
+				os.Exit(0)
+			}
+			verifyCtx, span := tracing.Start(batchCtx, "verify-pod")
+			if !settlement.VerifyBatch(verifyCtx, batchNumber, proof, ldda, lds) {
+				err := fmt.Errorf("verification of pod %d was rejected", batchNumber)
+				tracing.End(span, err)
+				logger.Error(fmt.Sprintf("Error in verifying batch %d to settlement client", batchNumber))
+				failBatch(ldbatch, batchNumber, "settlement-verify", err)
+				return
+			}
+			tracing.End(span, nil)
+			advanceBatch(ldbatch, state, StageVerified)
+		}
+
+		err := recordSettledBatch(lds, batchNumber, state.TxEnd)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error in recording settled batch %d : %s", batchNumber, err.Error()))
+			os.Exit(0)
+		}
+
+		logger.Warn(fmt.Sprintf("Successfully saved Batch %d in the latest phase", batchNumber))
+	}
+}
+
+func getBatch(ldbatch *leveldb.DB, batchNumber int) (*types.BatchStruct, error) {
+	batchBytes, err := ldbatch.Get([]byte(fmt.Sprintf("batch-%d", batchNumber)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var batch types.BatchStruct
+	if err := json.Unmarshal(batchBytes, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// ReconciliationReport compares the local batch count with the latest pod of the station
+// on the settlement layer, and records what was done about it.
+type ReconciliationReport struct {
+	LocalPod    int    `json:"local_pod"`
+	SettledPod  int    `json:"settled_pod"`
+	LocalRoot   string `json:"local_root"`
+	SettledRoot string `json:"settled_root"`
+	Verified    bool   `json:"verified"`
+	Action      string `json:"action"`
+	Discrepancy string `json:"discrepancy,omitempty"`
+}
+
+// podRanges is the part of prover.Aggregator used to reconcile pod ranges.
+type podRanges interface {
+	Size() int
+	EndsRange(batchNum int) bool
+	Aggregate(firstBatch int) (*prover.AggregationResult, error)
+}
+
+// ReconcileWithSettlement is run on startup before batches are generated. It resumes when
+// the settlement layer and the local stores agree, catches up when settlement is one pod,
+// or with an aggregator one pod range, ahead (the sequencer stopped before recording a
+// settled batch), resubmits local pods the settlement layer does not have, and returns an
+// error when the two cannot be reconciled.
+func ReconcileWithSettlement(ctx context.Context, settlement settlement_client.SettlementClient, lds *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, aggregator *prover.Aggregator) (*ReconciliationReport, error) {
+	var ranges podRanges
+	if aggregator != nil {
+		ranges = aggregator
+	}
+	return reconcile(ctx, settlement, lds, ldbatch, ldda, ranges)
+}
+
+func reconcile(ctx context.Context, settlement settlement_client.SettlementClient, lds *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, ranges podRanges) (*ReconciliationReport, error) {
+	settlementChainInfoByte, err := lds.Get([]byte("settlementChainInfo"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("station id is not in the static db, the station was registered from another data directory")
+	}
+	var settlementChainInfo types.SettlementLayerChainInfoStruct
+	if err := json.Unmarshal(settlementChainInfoByte, &settlementChainInfo); err != nil {
+		return nil, err
+	}
+
+	batchCount, err := lds.Get([]byte("batchCount"), nil)
+	if err != nil {
+		return nil, err
+	}
+	localPod, _ := strconv.Atoi(strings.TrimSpace(string(batchCount)))
+
+	latestPod, err := settlement.LatestPod(ctx, settlementChainInfo.ChainId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconciliationReport{
+		LocalPod:    localPod,
+		SettledPod:  int(latestPod.PodNumber),
+		SettledRoot: latestPod.MerkleRootHash,
+		Verified:    latestPod.Verified,
+	}
+
+	if report.SettledPod > 0 {
+		settledDa, err := getDa(ldda, report.SettledPod)
+		if err != nil {
+			report.Action = "halt"
+			report.Discrepancy = fmt.Sprintf("settlement holds pod %d, which is not in the local DA db", report.SettledPod)
+			return report, errors.New(report.Discrepancy)
+		}
+		if settledDa.CurrentStateHash != latestPod.MerkleRootHash {
+			report.LocalRoot = settledDa.CurrentStateHash
+			report.Action = "halt"
+			report.Discrepancy = fmt.Sprintf("root of pod %d differs between settlement and the local DA db", report.SettledPod)
+			return report, errors.New(report.Discrepancy)
+		}
+	}
+	if localPod > 0 {
+		localDa, err := getDa(ldda, localPod)
+		if err != nil {
+			return nil, err
+		}
+		report.LocalRoot = localDa.CurrentStateHash
+	}
+
+	switch {
+	case report.SettledPod == localPod:
+		if report.SettledPod > 0 && !latestPod.Verified && ranges == nil {
+			report.Action = "reverify"
+			proof, err := storedProof(localPod)
+			if err != nil {
+				return report, err
+			}
+			if !settlement.VerifyBatch(ctx, localPod, proof, ldda, lds) {
+				return report, fmt.Errorf("verification of pod %d was rejected", localPod)
+			}
+			return report, nil
+		}
+		report.Action = "resume"
+		return report, nil
+
+	case report.SettledPod == localPod+1 && !latestPod.Verified:
+		state, err := GetBatchState(ldbatch, report.SettledPod)
+		if err == nil && (stageReached(state.Stage, StageDAPosted) || stageReached(state.ResumeStage, StageDAPosted)) {
+			// the sequencer stopped, or failed, before recording the pod as added; the
+			// settlement worker verifies it when the pipeline starts
+			report.Action = "resume"
+			state.Stage = StageSubmitted
+			state.FailedStage = ""
+			state.ResumeStage = ""
+			state.Error = ""
+			return report, putBatchState(ldbatch, state)
+		}
+		report.Action = "halt"
+		report.Discrepancy = fmt.Sprintf("pod %d was added but not verified and its batch was not recorded locally", report.SettledPod)
+		return report, errors.New(report.Discrepancy)
+
+	case report.SettledPod == localPod+1 || (latestPod.Verified && settlesRange(ranges, localPod, report.SettledPod)):
+		// the pods were verified, singly or as one range ending on a batch posted to DA
+		// locally, but the sequencer stopped before recording them
+		txEnd, txEndErr := settledBatchTxEnd(lds, ldbatch, report.SettledPod)
+		if txEndErr != nil {
+			report.Action = "halt"
+			report.Discrepancy = fmt.Sprintf("pod %d is settled but where its batch ends is unknown locally: %s", report.SettledPod, txEndErr.Error())
+			return report, errors.New(report.Discrepancy)
+		}
+		report.Action = "catch-up"
+		for batchNumber := localPod + 1; batchNumber <= report.SettledPod; batchNumber++ {
+			state, err := GetBatchState(ldbatch, batchNumber)
+			if err == leveldb.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			state.Stage = StageVerified
+			if err := putBatchState(ldbatch, state); err != nil {
+				return report, err
+			}
+		}
+		return report, recordSettledBatch(lds, report.SettledPod, txEnd)
+
+	case report.SettledPod > localPod:
+		report.Action = "halt"
+		report.Discrepancy = fmt.Sprintf("settlement is %d pods ahead of the local stores", report.SettledPod-localPod)
+		return report, errors.New(report.Discrepancy)
+
+	default:
+		report.Action = "resubmit"
+		return report, resubmitPods(ctx, settlement, lds, ldbatch, ldda, ranges, report.SettledPod, localPod)
+	}
+}
+
+// settlesRange reports whether settledPod ends the pod range that follows localPod.
+func settlesRange(ranges podRanges, localPod int, settledPod int) bool {
+	return ranges != nil && settledPod > localPod && settledPod-localPod <= ranges.Size() && ranges.EndsRange(settledPod)
+}
+
+// resubmitPods sends pods settledPod+1 to localPod to the settlement layer again.
+func resubmitPods(ctx context.Context, settlement settlement_client.SettlementClient, lds *leveldb.DB, ldbatch *leveldb.DB, ldda *leveldb.DB, ranges podRanges, settledPod int, localPod int) error {
+	if ranges != nil {
+		if settledPod > 0 && !ranges.EndsRange(settledPod) {
+			return fmt.Errorf("settled pod %d is not the end of an aggregation range", settledPod)
+		}
+		for first := settledPod + 1; first+ranges.Size()-1 <= localPod; first += ranges.Size() {
+			aggregation, err := ranges.Aggregate(first)
+			if err != nil {
+				return err
+			}
+			if !settlement.AddPodRange(ctx, aggregation, lds) {
+				return fmt.Errorf("pod range %d to %d was rejected", aggregation.FirstBatch, aggregation.LastBatch)
+			}
+		}
+		return nil
+	}
+
+	for podNumber := settledPod + 1; podNumber <= localPod; podNumber++ {
+		logs.Log.Warn(fmt.Sprintf("Resubmitting pod %d to settlement", podNumber))
+
+		if _, err := ldbatch.Get([]byte(fmt.Sprintf("batch-%d", podNumber)), nil); err != nil {
+			return fmt.Errorf("batch %d is not in the batches db: %w", podNumber, err)
+		}
+		proof, err := storedProof(podNumber)
+		if err != nil {
+			return err
+		}
+		witnessVector, err := prover.WitnessVector(podNumber)
+		if err != nil {
+			return err
+		}
+		da, err := getDa(ldda, podNumber)
+		if err != nil {
+			return err
+		}
+
+		if settlement.AddBatch(ctx, witnessVector, podNumber, da.CurrentStateHash, uint64(time.Now().Unix()), lds, ldda) == "nil" {
+			return fmt.Errorf("pod %d was rejected", podNumber)
+		}
+		if !settlement.VerifyBatch(ctx, podNumber, proof, ldda, lds) {
+			return fmt.Errorf("verification of pod %d was rejected", podNumber)
+		}
+	}
+
+	return nil
+}
+
+// settledBatchTxEnd returns the index of the last transaction of batchNumber, the batch
+// after the last one recorded as settled. It is read from the batch state, or for batches
+// collected before batch states were recorded, counted from the stored batch, whose
+// transactions follow batchStartIndex.
+func settledBatchTxEnd(lds *leveldb.DB, ldbatch *leveldb.DB, batchNumber int) (int, error) {
+	state, err := GetBatchState(ldbatch, batchNumber)
+	if err == nil {
+		return state.TxEnd, nil
+	}
+	if err != leveldb.ErrNotFound {
+		return 0, err
+	}
+
+	batch, err := getBatch(ldbatch, batchNumber)
+	if err != nil {
+		return 0, fmt.Errorf("batch %d is not in the batches db: %w", batchNumber, err)
+	}
+	txStart, err := batchTxEnd(lds, ldbatch, batchNumber-1)
+	if err != nil {
+		return 0, err
+	}
+	return txStart + len(batch.TransactionHash), nil
+}
+
+// recordSettledBatch records batchNumber, ending with transaction txEnd, as done, as the
+// settlement worker does once a batch is verified.
+func recordSettledBatch(lds *leveldb.DB, batchNumber int, txEnd int) error {
+	err := lds.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(txEnd)), nil)
+	if err != nil {
+		return err
+	}
+	err = lds.Put([]byte("batchCount"), []byte(strconv.Itoa(batchNumber)), nil)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile("data/batchCount.txt", []byte(strconv.Itoa(batchNumber)), 0666)
+}
+
+func getDa(ldda *leveldb.DB, batchNumber int) (*types.DAStruct, error) {
+	daBytes, err := ldda.Get([]byte(fmt.Sprintf("batch_%d", batchNumber)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var da types.DAStruct
+	if err := json.Unmarshal(daBytes, &da); err != nil {
+		return nil, err
+	}
+	return &da, nil
+}
+
+func storedProof(batchNumber int) ([]byte, error) {
+	proof, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_%d", batchNumber)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting proof of batch %d: %w", batchNumber, err)
+	}
+	return proof, nil
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/handlers/settlement-client/mock"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/types"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+func TestMain(m *testing.M) {
+	// the proof and public witness dbs and data/batchCount.txt are relative to the working
+	// directory
+	dir, err := os.MkdirTemp("", "handlers-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	air.InitProofDb()
+	air.InitPublicWitnessDb()
+
+	code := m.Run()
+	air.GetProofDbInstance().Close()
+	air.GetPublicWitnessDbInstance().Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func memDB(t *testing.T) *leveldb.DB {
+	t.Helper()
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSettledBatchTxEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      *types.BatchStateStruct
+		batchTxs   int
+		startIndex string
+		want       int
+		wantErr    string
+	}{
+		{name: "batch state", state: &types.BatchStateStruct{BatchNumber: 4, TxStart: 30, TxEnd: 37}, want: 37},
+		// batches collected before batch states need not hold BatchSize transactions
+		{name: "stored batch", batchTxs: 7, startIndex: "30", want: 37},
+		{name: "nothing stored", startIndex: "30", wantErr: "not in the batches db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lds, ldbatch := memDB(t), memDB(t)
+			if tt.startIndex != "" {
+				lds.Put([]byte("batchStartIndex"), []byte(tt.startIndex), nil)
+			}
+			if tt.state != nil {
+				if err := putBatchState(ldbatch, tt.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.batchTxs > 0 {
+				batchJSON, _ := json.Marshal(types.BatchStruct{TransactionHash: make([]string, tt.batchTxs)})
+				ldbatch.Put([]byte("batch-4"), batchJSON, nil)
+			}
+
+			got, err := settledBatchTxEnd(lds, ldbatch, 4)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+const reconcileStation = "station-1"
+
+// reconcilePodCircuit stands in for the batch circuit: its public inputs are the batch
+// number and the state root limbs the settlement layer checks.
+type reconcilePodCircuit struct {
+	BatchNumber       frontend.Variable    `gnark:",public"`
+	PreviousStateRoot [2]frontend.Variable `gnark:",public"`
+	StateRoot         [2]frontend.Variable `gnark:",public"`
+}
+
+func (c *reconcilePodCircuit) Define(api frontend.API) error {
+	api.ToBinary(c.BatchNumber, 64)
+	return nil
+}
+
+// reconcileRangeCircuit stands in for the aggregation circuit of ranges of two pods.
+type reconcileRangeCircuit struct {
+	FirstBatch         frontend.Variable       `gnark:",public"`
+	PreviousStateRoots [2][2]frontend.Variable `gnark:",public"`
+	StateRoots         [2][2]frontend.Variable `gnark:",public"`
+}
+
+func (c *reconcileRangeCircuit) Define(api frontend.API) error {
+	api.ToBinary(c.FirstBatch, 64)
+	return nil
+}
+
+// podRoot returns the hex state root of batch n, "0" for batch 0.
+func podRoot(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return strings.Repeat(fmt.Sprintf("%02x", n), 32)
+}
+
+func podRootLimbs(n int) [2]frontend.Variable {
+	value, _ := new(big.Int).SetString(podRoot(n), 16)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	return [2]frontend.Variable{new(big.Int).Rsh(value, 128), new(big.Int).And(value, mask)}
+}
+
+// reconcileProofs holds the keys of both circuits, the proofs and witness vectors of pods 1
+// and 2 and the aggregation of the range of pods 1 and 2, over BN254.
+var reconcileProofs struct {
+	once        sync.Once
+	vk          []byte
+	rangeVK     []byte
+	podProofs   [3][]byte
+	podVectors  [3][]byte
+	aggregation prover.AggregationResult
+}
+
+func setupReconcileProofs(t *testing.T) {
+	t.Helper()
+	reconcileProofs.once.Do(func() {
+		setup := func(circuit frontend.Circuit) (constraint.ConstraintSystem, groth16.ProvingKey, []byte) {
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pk, verifyingKey, err := groth16.Setup(ccs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vk, _ := json.Marshal(verifyingKey)
+			return ccs, pk, vk
+		}
+		prove := func(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, assignment frontend.Circuit) (proof []byte, vector []byte, binary []byte) {
+			w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := groth16.Prove(ccs, pk, w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			public, _ := w.Public()
+			proof, _ = json.Marshal(p)
+			vector, _ = json.Marshal(public.Vector())
+			binary, _ = public.MarshalBinary()
+			return proof, vector, binary
+		}
+
+		ccs, pk, vk := setup(&reconcilePodCircuit{})
+		reconcileProofs.vk = vk
+		for n := 1; n <= 2; n++ {
+			reconcileProofs.podProofs[n], reconcileProofs.podVectors[n], _ = prove(ccs, pk, &reconcilePodCircuit{BatchNumber: n, PreviousStateRoot: podRootLimbs(n - 1), StateRoot: podRootLimbs(n)})
+		}
+
+		ccs, pk, vk = setup(&reconcileRangeCircuit{})
+		reconcileProofs.rangeVK = vk
+		proof, _, publicWitness := prove(ccs, pk, &reconcileRangeCircuit{
+			FirstBatch:         1,
+			PreviousStateRoots: [2][2]frontend.Variable{podRootLimbs(0), podRootLimbs(1)},
+			StateRoots:         [2][2]frontend.Variable{podRootLimbs(1), podRootLimbs(2)},
+		})
+		reconcileProofs.aggregation = prover.AggregationResult{
+			FirstBatch:        1,
+			LastBatch:         2,
+			PreviousStateRoot: podRoot(0),
+			StateRoots:        []string{podRoot(1), podRoot(2)},
+			Proof:             proof,
+			PublicWitness:     publicWitness,
+		}
+	})
+}
+
+// testRanges aggregates ranges of two pods into the stored aggregation of pods 1 and 2.
+type testRanges struct{}
+
+func (testRanges) Size() int { return 2 }
+
+func (testRanges) EndsRange(batchNum int) bool { return batchNum%2 == 0 }
+
+func (testRanges) Aggregate(firstBatch int) (*prover.AggregationResult, error) {
+	if firstBatch != 1 {
+		return nil, fmt.Errorf("no aggregation of range %d", firstBatch)
+	}
+	aggregation := reconcileProofs.aggregation
+	return &aggregation, nil
+}
+
+// reconcileStores returns the static, batches and DA dbs of a station that recorded batch
+// settledCount as settled and posted batches up to posted to DA, with the proofs of pods 1
+// and 2 in the proof and public witness dbs. Batch n ends with transaction 10n.
+func reconcileStores(t *testing.T, settledCount int, posted int) (*leveldb.DB, *leveldb.DB, *leveldb.DB) {
+	t.Helper()
+	lds, ldbatch, ldda := memDB(t), memDB(t), memDB(t)
+
+	chainInfo, _ := json.Marshal(types.SettlementLayerChainInfoStruct{ChainId: reconcileStation})
+	lds.Put([]byte("settlementChainInfo"), chainInfo, nil)
+	lds.Put([]byte("batchCount"), []byte(fmt.Sprint(settledCount)), nil)
+	lds.Put([]byte("batchStartIndex"), []byte(fmt.Sprint(10*settledCount)), nil)
+
+	for n := 0; n <= posted; n++ {
+		da, _ := json.Marshal(types.DAStruct{
+			DAKey:             fmt.Sprintf("key%d", n),
+			DAClientName:      "local",
+			PreviousStateHash: podRoot(max(n-1, 0)),
+			CurrentStateHash:  podRoot(n),
+		})
+		ldda.Put([]byte(fmt.Sprintf("batch_%d", n)), da, nil)
+		if n == 0 {
+			continue
+		}
+
+		stage := StageDAPosted
+		if n <= settledCount {
+			stage = StageVerified
+		}
+		if err := putBatchState(ldbatch, &types.BatchStateStruct{BatchNumber: n, Stage: stage, TxStart: 10 * (n - 1), TxEnd: 10 * n, CurrentStateHash: podRoot(n)}); err != nil {
+			t.Fatal(err)
+		}
+		batchJSON, _ := json.Marshal(types.BatchStruct{TransactionHash: make([]string, 10)})
+		ldbatch.Put([]byte(fmt.Sprintf("batch-%d", n)), batchJSON, nil)
+	}
+
+	for n := 1; n <= 2; n++ {
+		air.GetProofDbInstance().Put([]byte(fmt.Sprintf("proof_%d", n)), reconcileProofs.podProofs[n], nil)
+		air.GetProofDbInstance().Put([]byte(fmt.Sprintf("proof_backend_%d", n)), []byte(prover.BackendGroth16), nil)
+		air.GetProofDbInstance().Put([]byte(fmt.Sprintf("proof_curve_%d", n)), []byte(prover.CurveBN254), nil)
+		air.GetPublicWitnessDbInstance().Put([]byte(fmt.Sprintf("witness_vector_%d", n)), reconcileProofs.podVectors[n], nil)
+	}
+	return lds, ldbatch, ldda
+}
+
+// newMockSettlement serves a mock settlement layer with the station registered.
+func newMockSettlement(t *testing.T) *settlement_client.HTTPClient {
+	t.Helper()
+	server := httptest.NewServer(mock.NewServer())
+	t.Cleanup(server.Close)
+
+	payload, _ := json.Marshal(settlement_client.PostAddExecutionLayerStruct{
+		VerificationKey:            reconcileProofs.vk,
+		ChainInfo:                  fmt.Sprintf(`{"chainID":%q}`, reconcileStation),
+		ProofBackend:               prover.BackendGroth16,
+		ProofCurve:                 prover.CurveBN254,
+		AggregationVerificationKey: reconcileProofs.rangeVK,
+		AggregationSize:            2,
+	})
+	res, err := http.Post(server.URL+"/add-station", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	return settlement_client.NewHTTPClient(server.URL, nil)
+}
+
+func TestReconcileWithSettlement(t *testing.T) {
+	setupReconcileProofs(t)
+
+	addPods := func(pods ...int) func(t *testing.T, settlement *settlement_client.HTTPClient, lds, ldda *leveldb.DB) {
+		return func(t *testing.T, settlement *settlement_client.HTTPClient, lds, ldda *leveldb.DB) {
+			for _, n := range pods {
+				if settlement.AddBatch(context.Background(), json.RawMessage(reconcileProofs.podVectors[n]), n, podRoot(n), 0, lds, ldda) == "nil" {
+					t.Fatalf("pod %d was rejected", n)
+				}
+			}
+		}
+	}
+	settlePods := func(pods ...int) func(t *testing.T, settlement *settlement_client.HTTPClient, lds, ldda *leveldb.DB) {
+		return func(t *testing.T, settlement *settlement_client.HTTPClient, lds, ldda *leveldb.DB) {
+			addPods(pods...)(t, settlement, lds, ldda)
+			for _, n := range pods {
+				if !settlement.VerifyBatch(context.Background(), n, reconcileProofs.podProofs[n], ldda, lds) {
+					t.Fatalf("verification of pod %d was rejected", n)
+				}
+			}
+		}
+	}
+	settleRange := func(t *testing.T, settlement *settlement_client.HTTPClient, lds, ldda *leveldb.DB) {
+		if !settlement.AddPodRange(context.Background(), &reconcileProofs.aggregation, lds) {
+			t.Fatal("pod range was rejected")
+		}
+	}
+
+	tests := []struct {
+		name         string
+		ranges       podRanges
+		settledCount int
+		posted       int
+		// settle brings the settlement layer to where the sequencer left it
+		settle func(t *testing.T, settlement *settlement_client.HTTPClient, lds, ldda *leveldb.DB)
+		// change alters the local stores after settling
+		change      func(ldbatch, ldda *leveldb.DB)
+		wantAction  string
+		wantErr     string
+		wantCount   int
+		wantStages  map[int]string
+		wantSettled int
+	}{
+		{name: "resume", settledCount: 1, posted: 2, settle: settlePods(1), wantAction: "resume", wantCount: 1, wantSettled: 1},
+		{name: "reverify", settledCount: 1, posted: 1, settle: addPods(1), wantAction: "reverify", wantCount: 1, wantSettled: 1},
+		{
+			name: "resume added pod", posted: 1, settle: addPods(1),
+			wantAction: "resume", wantStages: map[int]string{1: StageSubmitted},
+		},
+		{
+			name: "catch-up", posted: 2, settle: settlePods(1),
+			wantAction: "catch-up", wantCount: 1, wantStages: map[int]string{1: StageVerified, 2: StageDAPosted}, wantSettled: 1,
+		},
+		{
+			name: "catch-up range", ranges: testRanges{}, posted: 2, settle: settleRange,
+			wantAction: "catch-up", wantCount: 2, wantStages: map[int]string{1: StageVerified, 2: StageVerified}, wantSettled: 2,
+		},
+		{name: "resubmit", settledCount: 2, posted: 2, wantAction: "resubmit", wantCount: 2, wantSettled: 2},
+		{name: "resubmit range", ranges: testRanges{}, settledCount: 2, posted: 2, wantAction: "resubmit", wantCount: 2, wantSettled: 2},
+		{
+			name: "halt on another root", settledCount: 1, posted: 1, settle: settlePods(1),
+			change: func(ldbatch, ldda *leveldb.DB) {
+				da, _ := json.Marshal(types.DAStruct{CurrentStateHash: podRoot(3)})
+				ldda.Put([]byte("batch_1"), da, nil)
+			},
+			wantAction: "halt", wantErr: "root of pod 1 differs",
+		},
+		{
+			name: "halt on added pod not recorded", posted: 1, settle: addPods(1),
+			change: func(ldbatch, ldda *leveldb.DB) {
+				ldbatch.Delete([]byte("batch-state-1"), nil)
+			},
+			wantAction: "halt", wantErr: "was not recorded locally",
+		},
+		{name: "halt two pods ahead", posted: 2, settle: settlePods(1, 2), wantAction: "halt", wantErr: "2 pods ahead"},
+		{
+			name: "halt on range not posted", ranges: testRanges{}, posted: 2, settle: settleRange,
+			change: func(ldbatch, ldda *leveldb.DB) {
+				ldda.Delete([]byte("batch_2"), nil)
+			},
+			wantAction: "halt", wantErr: "pod 2, which is not in the local DA db",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.MkdirAll("data", 0755)
+			lds, ldbatch, ldda := reconcileStores(t, tt.settledCount, tt.posted)
+			settlement := newMockSettlement(t)
+			if tt.settle != nil {
+				tt.settle(t, settlement, lds, ldda)
+			}
+			if tt.change != nil {
+				tt.change(ldbatch, ldda)
+			}
+
+			report, err := reconcile(context.Background(), settlement, lds, ldbatch, ldda, tt.ranges)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if report.Action != tt.wantAction {
+				t.Fatalf("got action %q, want %q", report.Action, tt.wantAction)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			count, _ := getBatchCount(lds)
+			if count != tt.wantCount {
+				t.Errorf("batch count is %d, want %d", count, tt.wantCount)
+			}
+			if startIndex, _ := lds.Get([]byte("batchStartIndex"), nil); string(startIndex) != fmt.Sprint(10*tt.wantCount) {
+				t.Errorf("batch start index is %s, want %d", startIndex, 10*tt.wantCount)
+			}
+			for n, stage := range tt.wantStages {
+				state, err := GetBatchState(ldbatch, n)
+				if err != nil || state.Stage != stage {
+					t.Errorf("batch %d is %+v, %v, want stage %s", n, state, err, stage)
+				}
+			}
+			latest, err := settlement.LatestPod(context.Background(), reconcileStation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSettled > 0 && (int(latest.PodNumber) != tt.wantSettled || !latest.Verified) {
+				t.Errorf("settlement holds %+v, want pod %d verified", latest, tt.wantSettled)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Seal reasons of the sealing policy, recorded with every batch.
+const (
+	SealTxCount  = "tx-count"
+	SealMaxWait  = "max-wait"
+	SealCalldata = "calldata"
+	SealGas      = "gas"
+)
+
+// sealPolicy tracks the transactions of a batch being collected against the limits that
+// seal it. A limit of 0 is not applied.
+type sealPolicy struct {
+	maxTxs      int
+	maxWait     time.Duration
+	maxCalldata uint64
+	maxGas      uint64
+
+	txs         int
+	firstTxTime time.Time
+	calldata    uint64
+	gas         uint64
+}
+
+// newSealPolicy returns the policy of BatchMaxTxs, BatchMaxWait, BatchMaxCalldata and
+// BatchMaxGas, holding at most BatchSize transactions.
+func newSealPolicy() *sealPolicy {
+	maxTxs := common.BatchSize
+	if common.BatchMaxTxs > 0 && common.BatchMaxTxs < maxTxs {
+		maxTxs = common.BatchMaxTxs
+	}
+	return &sealPolicy{
+		maxTxs:      maxTxs,
+		maxWait:     common.BatchMaxWait * time.Second,
+		maxCalldata: common.BatchMaxCalldata,
+		maxGas:      common.BatchMaxGas,
+	}
+}
+
+// full returns SealTxCount once the batch holds maxTxs transactions.
+func (p *sealPolicy) full() string {
+	if p.txs >= p.maxTxs {
+		return SealTxCount
+	}
+	return ""
+}
+
+// expired returns SealMaxWait once maxWait has passed at now since the block of the first
+// transaction of the batch.
+func (p *sealPolicy) expired(now time.Time) string {
+	if p.txs > 0 && p.maxWait > 0 && now.Sub(p.firstTxTime) >= p.maxWait {
+		return SealMaxWait
+	}
+	return ""
+}
+
+// exceeds returns the limit that a transaction with calldata bytes of input and a gas
+// limit of gas would take the batch over. A transaction over a limit on its own still gets
+// a batch of its own.
+func (p *sealPolicy) exceeds(calldata uint64, gas uint64) string {
+	if p.txs == 0 {
+		return ""
+	}
+	if p.maxCalldata > 0 && p.calldata+calldata > p.maxCalldata {
+		return SealCalldata
+	}
+	if p.maxGas > 0 && p.gas+gas > p.maxGas {
+		return SealGas
+	}
+	return ""
+}
+
+// add counts a transaction of the block made at blockTime in the batch.
+func (p *sealPolicy) add(calldata uint64, gas uint64, blockTime time.Time) {
+	if p.txs == 0 {
+		p.firstTxTime = blockTime
+	}
+	p.txs++
+	p.calldata += calldata
+	p.gas += gas
+}
+
+// calldataSize returns the number of bytes of the 0x prefixed hex input of a transaction.
+func calldataSize(input string) uint64 {
+	return uint64(len(strings.TrimPrefix(input, "0x")) / 2)
+}
+
+// blockTime returns the timestamp of block blockNumber stored in ldb.
+func blockTime(ldb *leveldb.DB, blockNumber uint64) (time.Time, error) {
+	blockBytes, err := ldb.Get([]byte(fmt.Sprintf("block_%d", blockNumber)), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var block types.BlockStruct
+	if err := json.Unmarshal(blockBytes, &block); err != nil {
+		return time.Time{}, err
+	}
+	timestamp, err := strconv.ParseInt(block.Timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", block.Timestamp, err)
+	}
+	return time.Unix(timestamp, 0), nil
+}
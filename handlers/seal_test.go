@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+func TestSealPolicy(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	type tx struct {
+		calldata uint64
+		gas      uint64
+		at       time.Duration
+	}
+	tests := []struct {
+		name       string
+		policy     sealPolicy
+		txs        []tx
+		now        time.Duration
+		wantTxs    int
+		wantReason string
+	}{
+		{
+			name:       "tx count",
+			policy:     sealPolicy{maxTxs: 2},
+			txs:        []tx{{}, {}, {}},
+			wantTxs:    2,
+			wantReason: SealTxCount,
+		},
+		{
+			name:       "calldata",
+			policy:     sealPolicy{maxTxs: 10, maxCalldata: 100},
+			txs:        []tx{{calldata: 60}, {calldata: 40}, {calldata: 1}},
+			wantTxs:    2,
+			wantReason: SealCalldata,
+		},
+		{
+			name:       "gas",
+			policy:     sealPolicy{maxTxs: 10, maxGas: 50000},
+			txs:        []tx{{gas: 21000}, {gas: 21000}, {gas: 21000}},
+			wantTxs:    2,
+			wantReason: SealGas,
+		},
+		{
+			name:    "over a limit on its own",
+			policy:  sealPolicy{maxTxs: 10, maxCalldata: 100, maxGas: 50000},
+			txs:     []tx{{calldata: 500, gas: 90000}},
+			wantTxs: 1,
+		},
+		{
+			name:       "later block after the wait",
+			policy:     sealPolicy{maxTxs: 10, maxWait: time.Minute},
+			txs:        []tx{{at: 0}, {at: 59 * time.Second}, {at: 60 * time.Second}},
+			wantTxs:    2,
+			wantReason: SealMaxWait,
+		},
+		{
+			name:       "no transaction after the wait",
+			policy:     sealPolicy{maxTxs: 10, maxWait: time.Minute},
+			txs:        []tx{{at: 0}, {at: 10 * time.Second}},
+			now:        61 * time.Second,
+			wantTxs:    2,
+			wantReason: SealMaxWait,
+		},
+		{
+			name:    "waiting",
+			policy:  sealPolicy{maxTxs: 10, maxWait: time.Minute},
+			txs:     []tx{{at: 0}},
+			now:     30 * time.Second,
+			wantTxs: 1,
+		},
+		{
+			name:    "no limits",
+			policy:  sealPolicy{maxTxs: 10},
+			txs:     []tx{{calldata: 1 << 20, gas: 1 << 40, at: 0}, {calldata: 1 << 20, gas: 1 << 40, at: time.Hour}},
+			now:     2 * time.Hour,
+			wantTxs: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.policy
+			var reason string
+			for _, tx := range tt.txs {
+				if reason = p.full(); reason != "" {
+					break
+				}
+				if reason = p.expired(start.Add(tx.at)); reason != "" {
+					break
+				}
+				if reason = p.exceeds(tx.calldata, tx.gas); reason != "" {
+					break
+				}
+				p.add(tx.calldata, tx.gas, start.Add(tx.at))
+			}
+			if reason == "" && tt.now > 0 {
+				reason = p.expired(start.Add(tt.now))
+			}
+			if p.txs != tt.wantTxs || reason != tt.wantReason {
+				t.Fatalf("sealed %d transactions (%q), want %d (%q)", p.txs, reason, tt.wantTxs, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSealPolicyCatchUp(t *testing.T) {
+	// the first transaction is from a block long before the wall-clock, as while ingestion
+	// catches up with the chain
+	start := time.Unix(1700000000, 0)
+	p := sealPolicy{maxTxs: 10, maxWait: time.Minute}
+	p.add(0, 21000, start)
+
+	c := &Controller{}
+	c.blockIngested(start.Add(30 * time.Second))
+	if reason := p.expired(c.ingestionTime()); reason != "" {
+		t.Fatalf("sealed (%q) 30 seconds of blocks after the first transaction", reason)
+	}
+	c.blockIngested(start.Add(61 * time.Second))
+	if reason := p.expired(c.ingestionTime()); reason != SealMaxWait {
+		t.Fatalf("got %q 61 seconds of blocks after the first transaction, want %q", reason, SealMaxWait)
+	}
+
+	// at the chain head no block may come, so the wait is measured by the wall-clock
+	c = &Controller{}
+	c.blockIngested(start)
+	c.chainHeadReached()
+	if reason := p.expired(c.ingestionTime()); reason != SealMaxWait {
+		t.Fatalf("got %q at the chain head, want %q", reason, SealMaxWait)
+	}
+}
+
+func TestCalldataSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+	}{
+		{"", 0},
+		{"0x", 0},
+		{"0xa9059cbb", 4},
+		{"a9059cbb", 4},
+	}
+	for _, tt := range tests {
+		if got := calldataSize(tt.input); got != tt.want {
+			t.Errorf("calldataSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBlockTime(t *testing.T) {
+	ldb := memDB(t)
+	block, _ := json.Marshal(types.BlockStruct{Number: "12", Timestamp: "1700000000"})
+	ldb.Put([]byte("block_12"), block, nil)
+
+	got, err := blockTime(ldb, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("got %v", got)
+	}
+	if _, err := blockTime(ldb, 13); err == nil {
+		t.Fatal("expected an error for a missing block")
+	}
+}
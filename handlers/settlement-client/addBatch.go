@@ -1,16 +1,13 @@
 package settlement_client
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"time"
 
-	"github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
 	"github.com/airchains-network/evm-sequencer-node/types"
 	"github.com/syndtr/goleveldb/leveldb"
 )
@@ -22,24 +19,25 @@ type PostAddBatchStruct struct {
 	PreviousMerkleRootHash string `json:"previous_merkle_root_hash"`
 	PublicWitness          []byte `json:"public_witness"`
 	Timestamp              uint64 `json:"timestamp"`
+	DACommitmentStruct
+	SignatureStruct
 }
 
-func AddBatch(witnessVector any, batchNumber int, mrh string, timestamp uint64, lds *leveldb.DB, ldda *leveldb.DB) string {
+func (c *HTTPClient) AddBatch(ctx context.Context, witnessVector any, batchNumber int, mrh string, timestamp uint64, lds *leveldb.DB, ldda *leveldb.DB) string {
+	logger := logs.Log.With("batch", batchNumber)
 
-	fmt.Println("Creating batch: ", batchNumber)
-
-	logs.Log.Warn("Submitting batch to settlement")
+	logger.Warn("Submitting batch to settlement")
 
 	settlementChainInfoByte, err := lds.Get([]byte("settlementChainInfo"), nil)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting settlementChainInfo from static db : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in getting settlementChainInfo from static db : %s", err.Error()))
 		return "nil"
 	}
 
 	var settlementChainInfo types.SettlementLayerChainInfoStruct
 	err = json.Unmarshal(settlementChainInfoByte, &settlementChainInfo)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in unmarshalling settlementChainInfo : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in unmarshalling settlementChainInfo : %s", err.Error()))
 		return "nil"
 	}
 	chainID := settlementChainInfo.ChainId
@@ -50,14 +48,20 @@ func AddBatch(witnessVector any, batchNumber int, mrh string, timestamp uint64,
 
 	daGet, daGetErr := ldda.Get([]byte(fmt.Sprintf("batch_%d", batchNumber-1)), nil)
 	if daGetErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting da from db : %s", daGetErr.Error()))
+		logger.Error(fmt.Sprintf("Error in getting da from db : %s", daGetErr.Error()))
 		os.Exit(0)
 	}
 
 	var daDecode types.DAStruct
 	daDecodeErr := json.Unmarshal(daGet, &daDecode)
 	if daDecodeErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error in unmarshalling da : %s", daDecodeErr.Error()))
+		logger.Error(fmt.Sprintf("Error in unmarshalling da : %s", daDecodeErr.Error()))
+		os.Exit(0)
+	}
+
+	commitment, err := daCommitment(ldda, batchNumber)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in getting da commitment : %s", err.Error()))
 		os.Exit(0)
 	}
 
@@ -76,48 +80,28 @@ func AddBatch(witnessVector any, batchNumber int, mrh string, timestamp uint64,
 		PreviousMerkleRootHash: pMrh,
 		PublicWitness:          wvByte,
 		Timestamp:              timestamp,
+		DACommitmentStruct:     commitment,
 	}
 
-	jsonData, err := json.Marshal(postAddBatchStruct)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling postAddBatchStruct : %s", err.Error()))
-		return "nil"
-	}
-	rpcUrl := fmt.Sprintf("%s/add-pod", common.SettlementClientRPC)
-	req, err := http.NewRequest("POST", rpcUrl, bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return "nil"
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request:", err)
+	if err := c.sign(&postAddBatchStruct, &postAddBatchStruct.SignatureStruct); err != nil {
+		logger.Error(fmt.Sprintf("Error in signing postAddBatchStruct : %s", err.Error()))
 		return "nil"
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	jsonData, err := json.Marshal(postAddBatchStruct)
 	if err != nil {
-		fmt.Println("Error reading response:", err)
+		logger.Error(fmt.Sprintf("Error in marshalling postAddBatchStruct : %s", err.Error()))
 		return "nil"
 	}
-
-	var response types.SettlementClientResponseStruct
-	err = json.Unmarshal(body, &response)
+	response, err := c.post(ctx, logger, "/add-pod", jsonData)
 	if err != nil {
-		fmt.Println("Error unmarshalling response:", err)
 		return "nil"
 	}
-
 	if !response.Status {
-		logs.Log.Error("error in adding batch to settlement")
-		logs.Log.Warn("Retrying in 5 seconds...")
-		time.Sleep(5 * time.Second)
-		AddBatch(witnessVector, batchNumber, mrh, timestamp, lds, ldda)
+		logger.Error(fmt.Sprintf("Error in adding batch to settlement : %s", response.Description))
+		return "nil"
 	}
 
+	events.Publish(events.TypeBatchSubmitted, events.BatchSubmitted{Batch: batchNumber, StationId: chainID, MerkleRootHash: mrh})
 	return response.Data
 }
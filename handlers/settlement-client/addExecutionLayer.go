@@ -2,12 +2,15 @@ package settlement_client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
 	"github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/prover"
 	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
 	"io"
 	"net/http"
 	"os"
@@ -17,21 +20,28 @@ import (
 type PostAddExecutionLayerStruct struct {
 	VerificationKey []byte `json:"verification_key"`
 	ChainInfo       string `json:"chain_info"`
+	ProofBackend    string `json:"proof_backend"`
+	ProofCurve      string `json:"proof_curve"`
+
+	AggregationVerificationKey []byte `json:"aggregation_verification_key,omitempty"`
+	AggregationSize            int    `json:"aggregation_size,omitempty"`
+	SignatureStruct
 }
 
-func AddExecutionLayer() string {
+func (c *HTTPClient) AddExecutionLayer(ctx context.Context) string {
 
 	logs.Log.Info("Adding execution layer")
 
-	if _, err := os.Stat("verificationKey.json"); os.IsNotExist(err) {
+	verificationKeyFile := prover.VerificationKeyFile(common.ProverBackend, common.ProverCurve)
+	if _, err := os.Stat(verificationKeyFile); os.IsNotExist(err) {
 		logs.Log.Error("Verification key not found. Retrying in 5 seconds...")
 		time.Sleep(5 * time.Second)
-		AddExecutionLayer()
+		c.AddExecutionLayer(ctx)
 	}
 
-	verificationKeyContents, err := os.ReadFile("verificationKey.json")
+	verificationKeyContents, err := os.ReadFile(verificationKeyFile)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
+		logs.Log.Error(fmt.Sprintf("Error reading file : %s", err.Error()))
 		return "nil"
 	}
 
@@ -39,7 +49,7 @@ func AddExecutionLayer() string {
 
 	chainInfoFile, err := os.ReadFile("config/chainInfo.json")
 	if err != nil {
-		fmt.Println("Error reading file:", err)
+		logs.Log.Error(fmt.Sprintf("Error reading file : %s", err.Error()))
 		logs.Log.Error("Error reading chainInfo.json file")
 		os.Exit(0)
 	}
@@ -48,14 +58,14 @@ func AddExecutionLayer() string {
 
 	err = json.Unmarshal(chainInfoFile, &chainInfo)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
+		logs.Log.Error(fmt.Sprintf("Error reading file : %s", err.Error()))
 		logs.Log.Error("Error reading chainInfo.json file")
 		os.Exit(0)
 	}
 
 	chainInfoAsString, err := json.Marshal(chainInfo.ChainInfo)
 	if err != nil {
-		fmt.Println("Error marshalling chain info:", err)
+		logs.Log.Error(fmt.Sprintf("Error marshalling chain info : %s", err.Error()))
 		return "nil"
 	}
 
@@ -63,71 +73,113 @@ func AddExecutionLayer() string {
 		//VerificationKey: verificationKeyContentsAsString,
 		VerificationKey: verificationKeyContents,
 		ChainInfo:       string(chainInfoAsString),
+		ProofBackend:    common.ProverBackend,
+		ProofCurve:      common.ProverCurve,
+	}
+
+	if common.AggregationSize > 0 {
+		aggregationVerificationKeyContents, err := os.ReadFile(prover.AggregationVerificationKeyFile(common.ProverCurve))
+		if err != nil {
+			logs.Log.Error(fmt.Sprintf("Error reading file : %s", err.Error()))
+			return "nil"
+		}
+		postAddExecutionLayerStruct.AggregationVerificationKey = aggregationVerificationKeyContents
+		postAddExecutionLayerStruct.AggregationSize = common.AggregationSize
+	}
+
+	if err := c.sign(&postAddExecutionLayerStruct, &postAddExecutionLayerStruct.SignatureStruct); err != nil {
+		logs.Log.Error(fmt.Sprintf("Error signing postAddExecutionLayerStruct : %s", err.Error()))
+		return "nil"
 	}
 
 	jsonData, err := json.Marshal(postAddExecutionLayerStruct)
 	if err != nil {
-		fmt.Println("Error marshalling postAddExecutionLayerStruct:", err)
+		logs.Log.Error(fmt.Sprintf("Error marshalling postAddExecutionLayerStruct : %s", err.Error()))
 		return "nil"
 	}
-	rpcUrl := fmt.Sprintf("%s/add-station", common.SettlementClientRPC)
-	req, err := http.NewRequest("POST", rpcUrl, bytes.NewBuffer(jsonData))
+	rpcUrl := fmt.Sprintf("%s/add-station", c.rpc)
+	req, err := http.NewRequestWithContext(ctx, "POST", rpcUrl, bytes.NewBuffer(jsonData))
 	if err != nil {
-		fmt.Println("Error creating request:", err)
+		logs.Log.Error(fmt.Sprintf("Error creating request : %s", err.Error()))
 		return "nil"
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
+		logs.Log.Error(fmt.Sprintf("Error sending request : %s", err.Error()))
 		return "nil"
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println("Error closing body:", err)
+			logs.Log.Error(fmt.Sprintf("Error closing body : %s", err.Error()))
 		}
 	}(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Println("Error reading response:", err)
+		logs.Log.Error(fmt.Sprintf("Error reading response : %s", err.Error()))
 		return "nil"
 	}
 
 	var response types.SettlementClientResponseStruct
 	err = json.Unmarshal(body, &response)
 	if err != nil {
-		fmt.Println("Error unmarshalling response:", err)
+		logs.Log.Error(fmt.Sprintf("Error unmarshalling response : %s", err.Error()))
 		return "nil"
 	}
-	
-	fmt.Println(response.Status)
-	fmt.Println(response.Data)
-	fmt.Println(response.Description)
+
+	logs.Log.Info("Settlement layer response", "status", response.Status, "data", response.Data, "description", response.Description)
 
 	if response.Data != "nil" && response.Data != "exist" {
 		var settlementChainInfo = types.SettlementLayerChainInfoStruct{
-			ChainId:   response.Data,
-			ChainName: chainInfo.ChainInfo.Moniker,
+			ChainId:        response.Data,
+			ChainName:      chainInfo.ChainInfo.Moniker,
+			CircuitVersion: prover.CircuitVersion,
 		}
 
 		settlementChainInfoBytes, err := json.Marshal(settlementChainInfo)
 		if err != nil {
-			fmt.Println("Error marshalling settlementChainInfo:", err)
+			logs.Log.Error(fmt.Sprintf("Error marshalling settlementChainInfo : %s", err.Error()))
 			return "nil"
 		}
 
 		err = air.GetStaticDbInstance().Put([]byte("settlementChainInfo"), settlementChainInfoBytes, nil)
 		if err != nil {
-			fmt.Println("Error putting settlementChainInfo:", err)
+			logs.Log.Error(fmt.Sprintf("Error putting settlementChainInfo : %s", err.Error()))
 			return "nil"
 		}
 	}
 
 	return response.Data
-}
\ No newline at end of file
+}
+
+// CheckRegisteredCircuit returns an error when the station in lds was registered with the
+// verification key of another version of the batch circuit. The settlement layer keeps the
+// key of the first registration and would reject every proof, so the station has to be
+// registered again under a new chain id.
+func CheckRegisteredCircuit(lds *leveldb.DB) error {
+	settlementChainInfoByte, err := lds.Get([]byte("settlementChainInfo"), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting settlementChainInfo from static db: %w", err)
+	}
+	var settlementChainInfo types.SettlementLayerChainInfoStruct
+	if err := json.Unmarshal(settlementChainInfoByte, &settlementChainInfo); err != nil {
+		return fmt.Errorf("error unmarshalling settlementChainInfo: %w", err)
+	}
+
+	registeredVersion := settlementChainInfo.CircuitVersion
+	if registeredVersion == 0 {
+		registeredVersion = 1
+	}
+	if registeredVersion != prover.CircuitVersion {
+		return fmt.Errorf("station %s was registered with the verification key of circuit version %d, this sequencer proves version %d; set a new chainID in config/chainInfo.json to register the station again", settlementChainInfo.ChainId, registeredVersion, prover.CircuitVersion)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package settlement_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// PostAddPodRangeStruct is the body of /add-pod-range. ProofBackend and ProofCurve are those
+// of the batch proofs of the range, read from the proof db; the aggregation proof itself is
+// always a Groth16 proof over BN254 checked against the aggregation verification key.
+type PostAddPodRangeStruct struct {
+	StationId              string   `json:"station_id"`
+	FirstPodNumber         uint64   `json:"first_pod_number"`
+	LastPodNumber          uint64   `json:"last_pod_number"`
+	PreviousMerkleRootHash string   `json:"previous_merkle_root_hash"`
+	MerkleRootHashes       []string `json:"merkle_root_hashes"`
+	ZkProof                []byte   `json:"zk_proof"`
+	PublicWitness          []byte   `json:"public_witness"`
+	ProofBackend           string   `json:"proof_backend"`
+	ProofCurve             string   `json:"proof_curve"`
+	SignatureStruct
+}
+
+// AddPodRange submits the aggregation proof of a range of consecutive pods, which settles
+// all of them at once in place of AddBatch and VerifyBatch for each pod.
+func (c *HTTPClient) AddPodRange(ctx context.Context, result *prover.AggregationResult, lds *leveldb.DB) bool {
+	logger := logs.Log.With("first_batch", result.FirstBatch, "last_batch", result.LastBatch)
+
+	logger.Warn(fmt.Sprintf("Adding pods %d to %d", result.FirstBatch, result.LastBatch))
+	settlementChainInfoByte, err := lds.Get([]byte("settlementChainInfo"), nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in getting settlementChainInfo from static db : %s", err.Error()))
+		return false
+	}
+
+	var settlementChainInfo types.SettlementLayerChainInfoStruct
+	err = json.Unmarshal(settlementChainInfoByte, &settlementChainInfo)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in unmarshalling settlementChainInfo : %s", err.Error()))
+		return false
+	}
+
+	proofBackend, proofCurve, err := rangeProofSystem(result.FirstBatch, result.LastBatch)
+	if err != nil {
+		logger.Error(err.Error())
+		return false
+	}
+
+	postAddPodRangeStruct := PostAddPodRangeStruct{
+		StationId:              settlementChainInfo.ChainId,
+		FirstPodNumber:         uint64(result.FirstBatch),
+		LastPodNumber:          uint64(result.LastBatch),
+		PreviousMerkleRootHash: result.PreviousStateRoot,
+		MerkleRootHashes:       result.StateRoots,
+		ZkProof:                result.Proof,
+		PublicWitness:          result.PublicWitness,
+		ProofBackend:           proofBackend,
+		ProofCurve:             proofCurve,
+	}
+
+	if err := c.sign(&postAddPodRangeStruct, &postAddPodRangeStruct.SignatureStruct); err != nil {
+		logger.Error(fmt.Sprintf("Error in signing postAddPodRangeStruct : %s", err.Error()))
+		return false
+	}
+
+	jsonData, err := json.Marshal(postAddPodRangeStruct)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in marshalling postAddPodRangeStruct : %s", err.Error()))
+		return false
+	}
+
+	response, err := c.post(ctx, logger, "/add-pod-range", jsonData)
+	if err != nil {
+		return false
+	}
+
+	if !response.Status {
+		logger.Error(fmt.Sprintf("Error in adding pod range : %s", response.Description))
+		return false
+	}
+
+	for batchNumber := result.FirstBatch; batchNumber <= result.LastBatch; batchNumber++ {
+		events.Publish(events.TypeBatchVerified, events.BatchVerified{
+			Batch:      batchNumber,
+			StationId:  settlementChainInfo.ChainId,
+			RangeFirst: result.FirstBatch,
+			RangeLast:  result.LastBatch,
+		})
+	}
+	return true
+}
+
+// rangeProofSystem returns the backend and curve of the proofs of batches firstBatch to
+// lastBatch, which must all have been proved with the same ones.
+func rangeProofSystem(firstBatch int, lastBatch int) (string, string, error) {
+	backend, curve := prover.ProofBackend(firstBatch), prover.ProofCurve(firstBatch)
+	for batchNumber := firstBatch + 1; batchNumber <= lastBatch; batchNumber++ {
+		if prover.ProofBackend(batchNumber) != backend || prover.ProofCurve(batchNumber) != curve {
+			return "", "", fmt.Errorf("batch %d was proved with %s over %s, batch %d with %s over %s", batchNumber, prover.ProofBackend(batchNumber), prover.ProofCurve(batchNumber), firstBatch, backend, curve)
+		}
+	}
+	return backend, curve, nil
+}
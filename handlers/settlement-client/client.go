@@ -0,0 +1,115 @@
+package settlement_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// SettlementClient registers the station with the settlement layer and submits its pods.
+type SettlementClient interface {
+	// AddExecutionLayer registers the station and returns its id, "exist" when it is
+	// already registered or "nil" on failure.
+	AddExecutionLayer(ctx context.Context) string
+	// AddBatch submits the public witness and state root of a pod, returning "nil" on failure.
+	AddBatch(ctx context.Context, witnessVector any, batchNumber int, mrh string, timestamp uint64, lds *leveldb.DB, ldda *leveldb.DB) string
+	// VerifyBatch submits the proof of a pod added by AddBatch.
+	VerifyBatch(ctx context.Context, batchNumber int, proofByte []byte, ldda *leveldb.DB, lds *leveldb.DB) bool
+	// AddPodRange submits the aggregation proof of a range of pods.
+	AddPodRange(ctx context.Context, result *prover.AggregationResult, lds *leveldb.DB) bool
+	// LatestPod returns the latest pod of the station known to the settlement layer.
+	LatestPod(ctx context.Context, stationId string) (*types.LatestPodStruct, error)
+}
+
+// HTTPClient is the SettlementClient of the settlement layer REST API served at rpc.
+// Requests are signed with key, or sent unsigned when key is nil, and recorded in the
+// settlement metrics and traces.
+type HTTPClient struct {
+	rpc        string
+	key        *keyring.Key
+	http       *http.Client
+	retryDelay time.Duration
+}
+
+func NewHTTPClient(rpc string, key *keyring.Key) *HTTPClient {
+	return &HTTPClient{
+		rpc:        rpc,
+		key:        key,
+		http:       &http.Client{Transport: tracing.Transport(metrics.SettlementClient.Transport)},
+		retryDelay: common.SettlementRetryDelay * time.Second,
+	}
+}
+
+// post sends jsonData to path of the settlement layer and returns its answer. A request
+// that fails or is answered without status is sent again, up to SettlementRetries attempts
+// SettlementRetryDelay seconds apart, and the last answer is returned. An error is only
+// returned when no attempt got an answer.
+func (c *HTTPClient) post(ctx context.Context, logger *slog.Logger, path string, jsonData []byte) (*types.SettlementClientResponseStruct, error) {
+	var response *types.SettlementClientResponseStruct
+	var err error
+	for attempt := 1; ; attempt++ {
+		var attemptResponse *types.SettlementClientResponseStruct
+		attemptResponse, err = c.postOnce(ctx, path, jsonData)
+		if err == nil {
+			response = attemptResponse
+			if response.Status {
+				return response, nil
+			}
+			logger.Error(fmt.Sprintf("Settlement layer rejected %s (attempt %d/%d) : %s", path, attempt, common.SettlementRetries, response.Description))
+		} else {
+			logger.Error(fmt.Sprintf("Error in sending %s (attempt %d/%d) : %s", path, attempt, common.SettlementRetries, err.Error()))
+		}
+
+		if attempt >= common.SettlementRetries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return response, ctx.Err()
+		case <-time.After(c.retryDelay):
+		}
+	}
+
+	if response == nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *HTTPClient) postOnce(ctx context.Context, path string, jsonData []byte) (*types.SettlementClientResponseStruct, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpc+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	var response types.SettlementClientResponseStruct
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+	return &response, nil
+}
@@ -0,0 +1,466 @@
+package settlement_client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/handlers/settlement-client/mock"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/types"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+const stationID = "station-1"
+
+// podCircuit stands in for the batch circuit: its first public inputs are the batch number
+// and the state root limbs, range checked as in the batch circuit so the proof is bound to
+// them, followed by a square.
+type podCircuit struct {
+	BatchNumber       frontend.Variable    `gnark:",public"`
+	PreviousStateRoot [2]frontend.Variable `gnark:",public"`
+	StateRoot         [2]frontend.Variable `gnark:",public"`
+	Y                 frontend.Variable    `gnark:",public"`
+	X                 frontend.Variable
+}
+
+func (c *podCircuit) Define(api frontend.API) error {
+	api.ToBinary(c.BatchNumber, 64)
+	for i := 0; i < 2; i++ {
+		api.ToBinary(c.PreviousStateRoot[i], 128)
+		api.ToBinary(c.StateRoot[i], 128)
+	}
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	return nil
+}
+
+// rangeCircuit stands in for the aggregation circuit of ranges of two pods, with the same
+// public inputs.
+type rangeCircuit struct {
+	FirstBatch         frontend.Variable       `gnark:",public"`
+	PreviousStateRoots [2][2]frontend.Variable `gnark:",public"`
+	StateRoots         [2][2]frontend.Variable `gnark:",public"`
+}
+
+func (c *rangeCircuit) Define(api frontend.API) error {
+	api.ToBinary(c.FirstBatch, 64)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			api.ToBinary(c.PreviousStateRoots[i][j], 128)
+			api.ToBinary(c.StateRoots[i][j], 128)
+		}
+	}
+	api.AssertIsEqual(c.PreviousStateRoots[1][0], c.StateRoots[0][0])
+	api.AssertIsEqual(c.PreviousStateRoots[1][1], c.StateRoots[0][1])
+	return nil
+}
+
+// root returns the hex state root of batch n, "0" for batch 0.
+func root(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return strings.Repeat(fmt.Sprintf("%02x", n), 32)
+}
+
+// limbs splits root(n) into its high and low 128 bits.
+func limbs(n int) [2]frontend.Variable {
+	value, _ := new(big.Int).SetString(root(n), 16)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	return [2]frontend.Variable{new(big.Int).Rsh(value, 128), new(big.Int).And(value, mask)}
+}
+
+// proofSet holds the verification keys of both circuits with proofs in the encodings the
+// sequencer sends. The proofs of pod 1 and of the range of pods 1 and 2 go from root(0)
+// through root(1) and root(2); the other proofs are valid for root(3) in place of the
+// last root.
+type proofSet struct {
+	vk                []byte
+	podProof          []byte
+	podVector         []byte
+	otherPodProof     []byte
+	otherPodVector    []byte
+	rangeVK           []byte
+	rangeProof        []byte
+	rangeWitness      []byte
+	otherRangeProof   []byte
+	otherRangeWitness []byte
+}
+
+var proofs proofSet
+
+func TestMain(m *testing.M) {
+	// the proof db is opened at a path relative to the working directory
+	dir, err := os.MkdirTemp("", "settlement-client-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	air.InitProofDb()
+	proofs = newProofSet()
+
+	code := m.Run()
+	air.GetProofDbInstance().Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newProofSet() proofSet {
+	prove := func(circuit frontend.Circuit, assignments ...frontend.Circuit) (vk []byte, proofsJSON [][]byte, vectors [][]byte, witnesses [][]byte) {
+		ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+		if err != nil {
+			panic(err)
+		}
+		pk, verifyingKey, err := groth16.Setup(ccs)
+		if err != nil {
+			panic(err)
+		}
+		vk, _ = json.Marshal(verifyingKey)
+		for _, assignment := range assignments {
+			w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+			if err != nil {
+				panic(err)
+			}
+			proof, err := groth16.Prove(ccs, pk, w)
+			if err != nil {
+				panic(err)
+			}
+			public, err := w.Public()
+			if err != nil {
+				panic(err)
+			}
+			proofJSON, _ := json.Marshal(proof)
+			vectorJSON, _ := json.Marshal(public.Vector())
+			publicBinary, _ := public.MarshalBinary()
+			proofsJSON = append(proofsJSON, proofJSON)
+			vectors = append(vectors, vectorJSON)
+			witnesses = append(witnesses, publicBinary)
+		}
+		return vk, proofsJSON, vectors, witnesses
+	}
+
+	var set proofSet
+	vk, podProofs, podVectors, _ := prove(&podCircuit{},
+		&podCircuit{BatchNumber: 1, PreviousStateRoot: limbs(0), StateRoot: limbs(1), X: 3, Y: 9},
+		&podCircuit{BatchNumber: 1, PreviousStateRoot: limbs(0), StateRoot: limbs(3), X: 3, Y: 9},
+	)
+	set.vk = vk
+	set.podProof, set.podVector = podProofs[0], podVectors[0]
+	set.otherPodProof, set.otherPodVector = podProofs[1], podVectors[1]
+
+	rangeVK, rangeProofs, _, rangeWitnesses := prove(&rangeCircuit{},
+		&rangeCircuit{FirstBatch: 1, PreviousStateRoots: [2][2]frontend.Variable{limbs(0), limbs(1)}, StateRoots: [2][2]frontend.Variable{limbs(1), limbs(2)}},
+		&rangeCircuit{FirstBatch: 1, PreviousStateRoots: [2][2]frontend.Variable{limbs(0), limbs(1)}, StateRoots: [2][2]frontend.Variable{limbs(1), limbs(3)}},
+	)
+	set.rangeVK = rangeVK
+	set.rangeProof, set.rangeWitness = rangeProofs[0], rangeWitnesses[0]
+	set.otherRangeProof, set.otherRangeWitness = rangeProofs[1], rangeWitnesses[1]
+	return set
+}
+
+// settlement is a mock settlement layer behind a proxy that counts requests and can
+// tamper with their bodies on the way.
+type settlement struct {
+	server   *httptest.Server
+	requests atomic.Int32
+	tamper   func(body map[string]any)
+}
+
+func newSettlement(t *testing.T) *settlement {
+	t.Helper()
+	s := &settlement{}
+	backend := mock.NewServer()
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if s.tamper != nil && r.Method == http.MethodPost {
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+				return
+			}
+			s.tamper(body)
+			tampered, _ := json.Marshal(body)
+			r.Body = io.NopCloser(bytes.NewReader(tampered))
+			r.ContentLength = int64(len(tampered))
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// register adds the station to the settlement layer, signed with key.
+func (s *settlement) register(t *testing.T, key *keyring.Key) {
+	t.Helper()
+	payload := settlement_client.PostAddExecutionLayerStruct{
+		VerificationKey:            proofs.vk,
+		ChainInfo:                  fmt.Sprintf(`{"chainID":%q}`, stationID),
+		ProofBackend:               prover.BackendGroth16,
+		ProofCurve:                 prover.CurveBN254,
+		AggregationVerificationKey: proofs.rangeVK,
+		AggregationSize:            2,
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	signBytes, err := keyring.SignBytes(payloadJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload.Signature, err = key.Sign(signBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload.PubKey = key.PubKey()
+	payloadJSON, _ = json.Marshal(payload)
+
+	res, err := http.Post(s.server.URL+"/add-station", "application/json", bytes.NewReader(payloadJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var response types.SettlementClientResponseStruct
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil || response.Data != stationID {
+		t.Fatalf("registering the station: %+v, %v", response, err)
+	}
+	s.requests.Store(0)
+}
+
+func newKey(t *testing.T) *keyring.Key {
+	t.Helper()
+	key, err := keyring.Create(t.TempDir(), "operator", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// stores returns the static and DA dbs of a station with batches 1 and 2 posted.
+func stores(t *testing.T) (*leveldb.DB, *leveldb.DB) {
+	t.Helper()
+	lds, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldda, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		lds.Close()
+		ldda.Close()
+	})
+
+	chainInfo, _ := json.Marshal(types.SettlementLayerChainInfoStruct{ChainId: stationID})
+	lds.Put([]byte("settlementChainInfo"), chainInfo, nil)
+
+	for batchNumber := 0; batchNumber <= 2; batchNumber++ {
+		previousRoot := "0"
+		if batchNumber > 0 {
+			previousRoot = root(batchNumber - 1)
+		}
+		da, _ := json.Marshal(types.DAStruct{
+			DAKey:             fmt.Sprintf("key%d", batchNumber),
+			DAClientName:      "local",
+			PreviousStateHash: previousRoot,
+			CurrentStateHash:  root(batchNumber),
+			BlobHash:          fmt.Sprintf("hash%d", batchNumber),
+		})
+		ldda.Put([]byte(fmt.Sprintf("batch_%d", batchNumber)), da, nil)
+	}
+
+	for batchNumber := 1; batchNumber <= 2; batchNumber++ {
+		air.GetProofDbInstance().Put([]byte(fmt.Sprintf("proof_backend_%d", batchNumber)), []byte(prover.BackendGroth16), nil)
+		air.GetProofDbInstance().Put([]byte(fmt.Sprintf("proof_curve_%d", batchNumber)), []byte(prover.CurveBN254), nil)
+	}
+
+	return lds, ldda
+}
+
+func TestAddAndVerifyBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		otherKey   bool
+		tamper     func(body map[string]any)
+		vector     []byte
+		proof      []byte
+		wantAdded  bool
+		wantVerify bool
+	}{
+		{name: "success", vector: proofs.podVector, proof: proofs.podProof, wantAdded: true, wantVerify: true},
+		{
+			name:   "tampered payload",
+			tamper: func(body map[string]any) { body["merkle_root_hash"] = "forged" },
+			vector: proofs.podVector,
+			proof:  proofs.podProof,
+		},
+		{name: "other key", otherKey: true, vector: proofs.podVector, proof: proofs.podProof},
+		{name: "proof of other roots", vector: proofs.podVector, proof: proofs.otherPodProof, wantAdded: true},
+		// the proof verifies against the witness, which does not state the roots of the pod
+		{name: "witness of other roots", vector: proofs.otherPodVector, proof: proofs.otherPodProof, wantAdded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stationKey := newKey(t)
+			s := newSettlement(t)
+			s.register(t, stationKey)
+			s.tamper = tt.tamper
+			lds, ldda := stores(t)
+
+			clientKey := stationKey
+			if tt.otherKey {
+				clientKey = newKey(t)
+			}
+			client := settlement_client.NewHTTPClient(s.server.URL, clientKey)
+			client.SetRetryDelay(time.Millisecond)
+			ctx := context.Background()
+
+			added := client.AddBatch(ctx, json.RawMessage(tt.vector), 1, root(1), 1700000000, lds, ldda)
+			if added := added != "nil"; added != tt.wantAdded {
+				t.Fatalf("AddBatch added %v, want %v", added, tt.wantAdded)
+			}
+			// rejected requests are retried, up to SettlementRetries attempts
+			if !tt.wantAdded {
+				if got := s.requests.Load(); got != 3 {
+					t.Fatalf("AddBatch sent %d requests, want 3", got)
+				}
+				return
+			}
+
+			s.requests.Store(0)
+			if verified := client.VerifyBatch(ctx, 1, tt.proof, ldda, lds); verified != tt.wantVerify {
+				t.Fatalf("VerifyBatch verified %v, want %v", verified, tt.wantVerify)
+			}
+			if want := map[bool]int32{true: 1, false: 3}[tt.wantVerify]; s.requests.Load() != want {
+				t.Fatalf("VerifyBatch sent %d requests, want %d", s.requests.Load(), want)
+			}
+
+			latest, err := client.LatestPod(ctx, stationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latest.PodNumber != 1 || latest.MerkleRootHash != root(1) || latest.Verified != tt.wantVerify {
+				t.Fatalf("unexpected latest pod %+v", latest)
+			}
+		})
+	}
+}
+
+func TestAddPodRange(t *testing.T) {
+	tests := []struct {
+		name          string
+		otherKey      bool
+		tamper        func(body map[string]any)
+		proof         []byte
+		witness       []byte
+		wantSubmitted bool
+	}{
+		{name: "success", proof: proofs.rangeProof, witness: proofs.rangeWitness, wantSubmitted: true},
+		{
+			name:    "tampered payload",
+			tamper:  func(body map[string]any) { body["last_pod_number"] = 3 },
+			proof:   proofs.rangeProof,
+			witness: proofs.rangeWitness,
+		},
+		{name: "other key", otherKey: true, proof: proofs.rangeProof, witness: proofs.rangeWitness},
+		{name: "proof of other roots", proof: proofs.otherRangeProof, witness: proofs.rangeWitness},
+		// the proof verifies against the witness sent, which does not state the roots of the range
+		{name: "witness of other roots", proof: proofs.otherRangeProof, witness: proofs.otherRangeWitness},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stationKey := newKey(t)
+			s := newSettlement(t)
+			s.register(t, stationKey)
+			s.tamper = tt.tamper
+			lds, _ := stores(t)
+
+			clientKey := stationKey
+			if tt.otherKey {
+				clientKey = newKey(t)
+			}
+			client := settlement_client.NewHTTPClient(s.server.URL, clientKey)
+			client.SetRetryDelay(time.Millisecond)
+
+			result := &prover.AggregationResult{
+				FirstBatch:        1,
+				LastBatch:         2,
+				PreviousStateRoot: root(0),
+				StateRoots:        []string{root(1), root(2)},
+				Proof:             tt.proof,
+				PublicWitness:     tt.witness,
+			}
+			if submitted := client.AddPodRange(context.Background(), result, lds); submitted != tt.wantSubmitted {
+				t.Fatalf("AddPodRange submitted %v, want %v", submitted, tt.wantSubmitted)
+			}
+
+			latest, err := client.LatestPod(context.Background(), stationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantLatest := uint64(0)
+			if tt.wantSubmitted {
+				wantLatest = 2
+			}
+			if latest.PodNumber != wantLatest {
+				t.Fatalf("latest pod is %d, want %d", latest.PodNumber, wantLatest)
+			}
+		})
+	}
+}
+
+func TestCheckRegisteredCircuit(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  *types.SettlementLayerChainInfoStruct
+		wantErr string
+	}{
+		{name: "not registered"},
+		{name: "current circuit", stored: &types.SettlementLayerChainInfoStruct{ChainId: stationID, CircuitVersion: prover.CircuitVersion}},
+		{name: "registered before versions", stored: &types.SettlementLayerChainInfoStruct{ChainId: stationID}, wantErr: "circuit version 1"},
+		{name: "older circuit", stored: &types.SettlementLayerChainInfoStruct{ChainId: stationID, CircuitVersion: prover.CircuitVersion - 1}, wantErr: "register the station again"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lds, err := leveldb.Open(storage.NewMemStorage(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lds.Close()
+			if tt.stored != nil {
+				chainInfo, _ := json.Marshal(tt.stored)
+				lds.Put([]byte("settlementChainInfo"), chainInfo, nil)
+			}
+
+			err = settlement_client.CheckRegisteredCircuit(lds)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
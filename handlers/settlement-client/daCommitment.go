@@ -0,0 +1,44 @@
+package settlement_client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// DACommitmentStruct links a pod to its data on the DA layer. It is embedded in the
+// add-pod and verify-pod payloads from SettlementPayloadVersion 2 on.
+type DACommitmentStruct struct {
+	Version    int    `json:"version,omitempty"`
+	DaProvider string `json:"da_provider,omitempty"`
+	DaKey      string `json:"da_key,omitempty"`
+	DaBlobHash string `json:"da_blob_hash,omitempty"`
+}
+
+// daCommitment reads the DA commitment of batchNumber from ldda. With
+// SettlementPayloadVersion 1 it is empty, so the payloads stay as they were.
+func daCommitment(ldda *leveldb.DB, batchNumber int) (DACommitmentStruct, error) {
+	if common.SettlementPayloadVersion < 2 {
+		return DACommitmentStruct{}, nil
+	}
+
+	daBytes, err := ldda.Get([]byte(fmt.Sprintf("batch_%d", batchNumber)), nil)
+	if err != nil {
+		return DACommitmentStruct{}, err
+	}
+
+	var da types.DAStruct
+	if err := json.Unmarshal(daBytes, &da); err != nil {
+		return DACommitmentStruct{}, err
+	}
+
+	return DACommitmentStruct{
+		Version:    common.SettlementPayloadVersion,
+		DaProvider: da.DAClientName,
+		DaKey:      da.DAKey,
+		DaBlobHash: da.BlobHash,
+	}, nil
+}
@@ -0,0 +1,8 @@
+package settlement_client
+
+import "time"
+
+// SetRetryDelay shortens the delay between attempts for tests.
+func (c *HTTPClient) SetRetryDelay(delay time.Duration) {
+	c.retryDelay = delay
+}
@@ -0,0 +1,46 @@
+package settlement_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+func (c *HTTPClient) LatestPod(ctx context.Context, stationId string) (*types.LatestPodStruct, error) {
+	rpcUrl := fmt.Sprintf("%s/latest-pod?station_id=%s", c.rpc, url.QueryEscape(stationId))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rpcUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response types.SettlementClientResponseStruct
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+	if !response.Status {
+		return nil, fmt.Errorf("error in getting latest pod : %s", response.Description)
+	}
+
+	var latestPod types.LatestPodStruct
+	if err := json.Unmarshal([]byte(response.Data), &latestPod); err != nil {
+		return nil, fmt.Errorf("error unmarshalling latest pod: %w", err)
+	}
+
+	return &latestPod, nil
+}
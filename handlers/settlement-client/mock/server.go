@@ -0,0 +1,324 @@
+// Package mock is an in-memory settlement layer serving the REST API used by
+// settlement_client.HTTPClient. It checks the pod chain and verifies every proof with the
+// key registered by the station against the pod numbers and roots it settles, so it can
+// stand in for the settlement layer in tests and local devnets.
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/types"
+)
+
+type station struct {
+	verificationKey            []byte
+	proofBackend               string
+	proofCurve                 string
+	aggregationVerificationKey []byte
+	pubKey                     []byte
+	pods                       map[uint64]*pod
+	latestPod                  uint64
+	latestVerifiedPod          uint64
+}
+
+type pod struct {
+	merkleRootHash         string
+	previousMerkleRootHash string
+	publicWitness          []byte
+	verified               bool
+}
+
+// Server is the mock settlement layer. It implements http.Handler.
+type Server struct {
+	mu       sync.Mutex
+	stations map[string]*station
+	mux      *http.ServeMux
+}
+
+func NewServer() *Server {
+	s := &Server{stations: make(map[string]*station), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/add-station", s.addStation)
+	s.mux.HandleFunc("/add-pod", s.addPod)
+	s.mux.HandleFunc("/verify-pod", s.verifyPod)
+	s.mux.HandleFunc("/add-pod-range", s.addPodRange)
+	s.mux.HandleFunc("/latest-pod", s.latestPod)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) addStation(w http.ResponseWriter, r *http.Request) {
+	var req settlement_client.PostAddExecutionLayerStruct
+	signer, ok := decode(w, r, &req)
+	if !ok {
+		return
+	}
+
+	var chainInfo struct {
+		ChainID string `json:"chainID"`
+	}
+	if err := json.Unmarshal([]byte(req.ChainInfo), &chainInfo); err != nil || chainInfo.ChainID == "" {
+		respond(w, false, "nil", "chain_info has no chainID")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.stations[chainInfo.ChainID]; ok {
+		respond(w, true, "exist", "station already exists")
+		return
+	}
+
+	s.stations[chainInfo.ChainID] = &station{
+		verificationKey:            req.VerificationKey,
+		proofBackend:               req.ProofBackend,
+		proofCurve:                 req.ProofCurve,
+		aggregationVerificationKey: req.AggregationVerificationKey,
+		pubKey:                     signer,
+		pods:                       make(map[uint64]*pod),
+	}
+	respond(w, true, chainInfo.ChainID, "station added")
+}
+
+func (s *Server) addPod(w http.ResponseWriter, r *http.Request) {
+	var req settlement_client.PostAddBatchStruct
+	signer, ok := decode(w, r, &req)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stations[req.StationId]
+	if !ok {
+		respond(w, false, "nil", fmt.Sprintf("unknown station %s", req.StationId))
+		return
+	}
+	if !authorized(w, st, signer) {
+		return
+	}
+	if req.PodNumber != st.latestPod+1 {
+		respond(w, false, "nil", fmt.Sprintf("expected pod %d, got %d", st.latestPod+1, req.PodNumber))
+		return
+	}
+	if previous, ok := st.pods[req.PodNumber-1]; ok && previous.merkleRootHash != req.PreviousMerkleRootHash {
+		respond(w, false, "nil", "previous merkle root does not match the previous pod")
+		return
+	}
+
+	st.pods[req.PodNumber] = &pod{
+		merkleRootHash:         req.MerkleRootHash,
+		previousMerkleRootHash: req.PreviousMerkleRootHash,
+		publicWitness:          req.PublicWitness,
+	}
+	st.latestPod = req.PodNumber
+	respond(w, true, "added", "pod added")
+}
+
+func (s *Server) verifyPod(w http.ResponseWriter, r *http.Request) {
+	var req settlement_client.VerifyBatchPostStruct
+	signer, ok := decode(w, r, &req)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stations[req.StationId]
+	if !ok {
+		respond(w, false, "nil", fmt.Sprintf("unknown station %s", req.StationId))
+		return
+	}
+	if !authorized(w, st, signer) {
+		return
+	}
+	p, ok := st.pods[req.PodNumber]
+	if !ok {
+		respond(w, false, "nil", fmt.Sprintf("pod %d was not added", req.PodNumber))
+		return
+	}
+	if p.merkleRootHash != req.MerkleRootHash {
+		respond(w, false, "nil", "merkle root does not match the added pod")
+		return
+	}
+	if req.ProofBackend != st.proofBackend || req.ProofCurve != st.proofCurve {
+		respond(w, false, "nil", fmt.Sprintf("station verifies %s proofs over %s", st.proofBackend, st.proofCurve))
+		return
+	}
+
+	// the proof is checked against the pod it was added with, not only the witness sent
+	if err := prover.CheckBatchStatement(p.publicWitness, int(req.PodNumber), p.previousMerkleRootHash, p.merkleRootHash); err != nil {
+		respond(w, false, "nil", err.Error())
+		return
+	}
+	publicWitness, err := prover.WitnessFromVector(st.proofCurve, p.publicWitness)
+	if err != nil {
+		respond(w, false, "nil", err.Error())
+		return
+	}
+	if err := prover.VerifyProof(st.proofBackend, st.proofCurve, st.verificationKey, req.ZkProof, publicWitness); err != nil {
+		respond(w, false, "nil", fmt.Sprintf("invalid proof: %s", err))
+		return
+	}
+
+	p.verified = true
+	if req.PodNumber > st.latestVerifiedPod {
+		st.latestVerifiedPod = req.PodNumber
+	}
+	respond(w, true, "verified", "pod verified")
+}
+
+func (s *Server) addPodRange(w http.ResponseWriter, r *http.Request) {
+	var req settlement_client.PostAddPodRangeStruct
+	signer, ok := decode(w, r, &req)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stations[req.StationId]
+	if !ok {
+		respond(w, false, "nil", fmt.Sprintf("unknown station %s", req.StationId))
+		return
+	}
+	if !authorized(w, st, signer) {
+		return
+	}
+	if st.aggregationVerificationKey == nil {
+		respond(w, false, "nil", "station did not register an aggregation key")
+		return
+	}
+	if req.ProofBackend != st.proofBackend || req.ProofCurve != st.proofCurve {
+		respond(w, false, "nil", fmt.Sprintf("station verifies %s proofs over %s", st.proofBackend, st.proofCurve))
+		return
+	}
+	if req.FirstPodNumber != st.latestPod+1 || req.LastPodNumber < req.FirstPodNumber {
+		respond(w, false, "nil", fmt.Sprintf("expected a range starting at pod %d", st.latestPod+1))
+		return
+	}
+	if len(req.MerkleRootHashes) != int(req.LastPodNumber-req.FirstPodNumber+1) {
+		respond(w, false, "nil", "one merkle root per pod is required")
+		return
+	}
+	if previous, ok := st.pods[req.FirstPodNumber-1]; ok && previous.merkleRootHash != req.PreviousMerkleRootHash {
+		respond(w, false, "nil", "previous merkle root does not match the previous pod")
+		return
+	}
+
+	// the statement is rebuilt from the range, so the proof is bound to the roots settled
+	publicWitness, err := prover.AggregationPublicWitness(int(req.FirstPodNumber), req.PreviousMerkleRootHash, req.MerkleRootHashes)
+	if err != nil {
+		respond(w, false, "nil", fmt.Sprintf("invalid pod range: %s", err))
+		return
+	}
+	expectedWitness, err := publicWitness.MarshalBinary()
+	if err != nil || !bytes.Equal(expectedWitness, req.PublicWitness) {
+		respond(w, false, "nil", "public witness does not match the pod range")
+		return
+	}
+	err = prover.VerifyProof(prover.BackendGroth16, prover.CurveBN254, st.aggregationVerificationKey, req.ZkProof, publicWitness)
+	if err != nil {
+		respond(w, false, "nil", fmt.Sprintf("invalid proof: %s", err))
+		return
+	}
+
+	previousRoot := req.PreviousMerkleRootHash
+	for i, root := range req.MerkleRootHashes {
+		podNumber := req.FirstPodNumber + uint64(i)
+		st.pods[podNumber] = &pod{merkleRootHash: root, previousMerkleRootHash: previousRoot, verified: true}
+		previousRoot = root
+	}
+	st.latestPod = req.LastPodNumber
+	st.latestVerifiedPod = req.LastPodNumber
+	respond(w, true, "verified", "pod range verified")
+}
+
+func (s *Server) latestPod(w http.ResponseWriter, r *http.Request) {
+	stationId := r.URL.Query().Get("station_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stations[stationId]
+	if !ok {
+		respond(w, false, "nil", fmt.Sprintf("unknown station %s", stationId))
+		return
+	}
+
+	latest := types.LatestPodStruct{PodNumber: st.latestPod}
+	if p, ok := st.pods[st.latestPod]; ok {
+		latest.MerkleRootHash = p.merkleRootHash
+		latest.Verified = p.verified
+	}
+
+	latestJSON, err := json.Marshal(latest)
+	if err != nil {
+		respond(w, false, "nil", err.Error())
+		return
+	}
+	respond(w, true, string(latestJSON), "latest pod")
+}
+
+// decode reads the request into v and checks its signature, if any. It returns the public
+// key that signed the request, nil for an unsigned one.
+func decode(w http.ResponseWriter, r *http.Request, v any) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, v)
+	}
+	if err != nil {
+		respond(w, false, "nil", fmt.Sprintf("invalid request: %s", err))
+		return nil, false
+	}
+
+	var signature settlement_client.SignatureStruct
+	if err := json.Unmarshal(body, &signature); err != nil || signature.Signature == nil {
+		return nil, true
+	}
+	signBytes, err := keyring.SignBytes(body)
+	if err != nil || !keyring.Verify(signature.PubKey, signBytes, signature.Signature) {
+		respond(w, false, "nil", "invalid signature")
+		return nil, false
+	}
+
+	return signature.PubKey, true
+}
+
+// authorized reports whether a request signed by signer may act for st. Stations
+// registered with a signed request only accept requests signed by the same key.
+func authorized(w http.ResponseWriter, st *station, signer []byte) bool {
+	if st.pubKey != nil && !bytes.Equal(st.pubKey, signer) {
+		respond(w, false, "nil", "request is not signed by the station key")
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, status bool, data string, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(types.SettlementClientResponseStruct{
+		Status:      status,
+		Data:        data,
+		Description: description,
+	})
+}
@@ -0,0 +1,38 @@
+package settlement_client
+
+import (
+	"encoding/json"
+
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+)
+
+// SignatureStruct authenticates a settlement request. It is embedded in every payload and
+// left out when the client has no key.
+type SignatureStruct struct {
+	PubKey    []byte `json:"pub_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// sign fills signature of payload with the operator key of the client.
+func (c *HTTPClient) sign(payload any, signature *SignatureStruct) error {
+	if c.key == nil {
+		return nil
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	signBytes, err := keyring.SignBytes(payloadJSON)
+	if err != nil {
+		return err
+	}
+
+	signature.Signature, err = c.key.Sign(signBytes)
+	if err != nil {
+		return err
+	}
+	signature.PubKey = c.key.PubKey()
+
+	return nil
+}
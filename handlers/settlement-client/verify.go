@@ -1,16 +1,15 @@
 package settlement_client
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/airchains-network/evm-sequencer-node/common"
+
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
+	"github.com/airchains-network/evm-sequencer-node/prover"
 	"github.com/airchains-network/evm-sequencer-node/types"
 	"github.com/syndtr/goleveldb/leveldb"
-	"io"
-	"net/http"
-	"time"
 )
 
 type VerifyBatchPostStruct struct {
@@ -19,6 +18,10 @@ type VerifyBatchPostStruct struct {
 	MerkleRootHash         string `json:"merkle_root_hash"`
 	PreviousMerkleRootHash string `json:"previous_merkle_root_hash"`
 	ZkProof                []byte `json:"zk_proof"`
+	ProofBackend           string `json:"proof_backend"`
+	ProofCurve             string `json:"proof_curve"`
+	DACommitmentStruct
+	SignatureStruct
 }
 
 //	BatchNumber    uint64 `json:"batch_number"`
@@ -28,41 +31,46 @@ type VerifyBatchPostStruct struct {
 //	ZkProof        []byte `json:"zk_proof"`
 //}
 
-func VerifyBatch(batchNumber int, proofByte []byte, ldda *leveldb.DB, lds *leveldb.DB) bool {
-	logs.Log.Warn("Verifying the batch ")
+func (c *HTTPClient) VerifyBatch(ctx context.Context, batchNumber int, proofByte []byte, ldda *leveldb.DB, lds *leveldb.DB) bool {
+	logger := logs.Log.With("batch", batchNumber)
+
+	logger.Warn("Verifying the batch")
 	settlementChainInfoByte, err := lds.Get([]byte("settlementChainInfo"), nil)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting settlementChainInfo from static db : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in getting settlementChainInfo from static db : %s", err.Error()))
 		return false
 	}
 
 	var settlementChainInfo types.SettlementLayerChainInfoStruct
 	err = json.Unmarshal(settlementChainInfoByte, &settlementChainInfo)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in unmarshalling settlementChainInfo : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in unmarshalling settlementChainInfo : %s", err.Error()))
 		return false
 	}
 	chainID := settlementChainInfo.ChainId
 
-	fmt.Println(batchNumber)
-
 	batchKey := fmt.Sprintf("batch_%d", batchNumber)
 	batchDetailsByte, err := ldda.Get([]byte(batchKey), nil)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in getting batch from db : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in getting batch from db : %s", err.Error()))
 		return false
 	}
 
 	var batchDetails types.DAStruct
 	err = json.Unmarshal(batchDetailsByte, &batchDetails)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in unmarshalling batchDetails : %s", err.Error()))
+		logger.Error(fmt.Sprintf("Error in unmarshalling batchDetails : %s", err.Error()))
 		return false
 	}
 
 	if batchNumber > 1 {
-		fmt.Println("batchDetails.PreviousStateHash", batchDetails.PreviousStateHash)
-		fmt.Println("batchDetails.CurrentStateHash", batchDetails.CurrentStateHash)
+		logger.Debug("Verifying state transition", "previous_state_hash", batchDetails.PreviousStateHash, "current_state_hash", batchDetails.CurrentStateHash)
+	}
+
+	commitment, err := daCommitment(ldda, batchNumber)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error in getting da commitment : %s", err.Error()))
+		return false
 	}
 
 	postVerifyBatchStruct := VerifyBatchPostStruct{
@@ -71,56 +79,31 @@ func VerifyBatch(batchNumber int, proofByte []byte, ldda *leveldb.DB, lds *level
 		MerkleRootHash:         batchDetails.CurrentStateHash,
 		PreviousMerkleRootHash: batchDetails.PreviousStateHash,
 		ZkProof:                proofByte,
+		ProofBackend:           prover.ProofBackend(batchNumber),
+		ProofCurve:             prover.ProofCurve(batchNumber),
+		DACommitmentStruct:     commitment,
 	}
 
-	jsonData, err := json.Marshal(postVerifyBatchStruct)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling postVerifyBatchStruct : %s", err.Error()))
-		return false
-	}
-
-	rpcUrl := fmt.Sprintf("%s/verify-pod", common.SettlementClientRPC)
-
-	req, err := http.NewRequest("POST", rpcUrl, bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
+	if err := c.sign(&postVerifyBatchStruct, &postVerifyBatchStruct.SignatureStruct); err != nil {
+		logger.Error(fmt.Sprintf("Error in signing postVerifyBatchStruct : %s", err.Error()))
 		return false
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	jsonData, err := json.Marshal(postVerifyBatchStruct)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
+		logger.Error(fmt.Sprintf("Error in marshalling postVerifyBatchStruct : %s", err.Error()))
 		return false
 	}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in closing response body : %s", err.Error()))
-		}
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
+	response, err := c.post(ctx, logger, "/verify-pod", jsonData)
 	if err != nil {
-		fmt.Println("Error reading response:", err)
 		return false
 	}
-
-	var response types.SettlementClientResponseStruct
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		fmt.Println("Error unmarshalling response:", err)
+	if !response.Status {
+		logger.Error(fmt.Sprintf("Error in verifying batch : %s", response.Description))
 		return false
 	}
 
-	if !response.Status {
-		logs.Log.Error(fmt.Sprintf("Error in verifying batch : %s", response.Description))
-		logs.Log.Warn("Trying again... in 5 seconds")
-		time.Sleep(5 * time.Second)
-		VerifyBatch(batchNumber, proofByte, ldda, lds)
-	}
-	return response.Status
+	events.Publish(events.TypeBatchVerified, events.BatchVerified{Batch: batchNumber, StationId: chainID})
+	return true
 }
@@ -12,6 +12,7 @@ import (
 
 	evmcommon "github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
 	evmtypes "github.com/airchains-network/evm-sequencer-node/types"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -20,8 +21,6 @@ import (
 )
 
 func insertTxn(db *leveldb.DB, txns evmtypes.TransactionStruct, transactionNumber int) error {
-
-	println("insertTxn")
 	data, err := json.Marshal(txns)
 	if err != nil {
 		return err
@@ -41,18 +40,18 @@ func insertTxn(db *leveldb.DB, txns evmtypes.TransactionStruct, transactionNumbe
 }
 
 func SaveTxns(client *ethclient.Client, ctx context.Context, ldt *leveldb.DB, transactionHash string, blockNumber int, blockHash string) {
+	logger := logs.Log.With("block", blockNumber, "tx", transactionHash)
+
 	blockNumberUint64, err := strconv.ParseUint(strconv.Itoa(blockNumber), 10, 64)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error parsing block number to uint64:"))
+		logger.Error(fmt.Sprintf("Error parsing block number to uint64:"))
 		time.Sleep(2 * time.Second)
-		logs.Log.Info("Retrying in 2s...")
+		logger.Info("Retrying in 2s...")
 		SaveTxns(client, ctx, ldt, transactionHash, blockNumber, blockHash)
 	}
 
 	txHash := common.HexToHash(transactionHash)
 
-	fmt.Println("txHash", txHash)
-
 	var tx *types.Transaction
 	var isPending bool
 	for {
@@ -60,32 +59,32 @@ func SaveTxns(client *ethclient.Client, ctx context.Context, ldt *leveldb.DB, tr
 		if err == nil {
 			break
 		} else {
-			logs.Log.Warn(fmt.Sprintf("Failed to get transaction by hash: %s", err))
-			logs.Log.Warn(fmt.Sprintf("Retrying in 2 seconds: %s", err))
+			logger.Warn(fmt.Sprintf("Failed to get transaction by hash: %s", err))
+			logger.Warn(fmt.Sprintf("Retrying in 2 seconds: %s", err))
 			time.Sleep(2 * time.Second)
 			continue
 		}
 	}
 
 	if isPending {
-		logs.Log.Warn("Transaction is pending")
-		logs.Log.Info(fmt.Sprintf("Transaction type: %d\n", tx.Type()))
+		logger.Warn("Transaction is pending")
+		logger.Info(fmt.Sprintf("Transaction type: %d\n", tx.Type()))
 	}
 
 	chainID, err := client.NetworkID(context.Background())
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to get the network ID: %v", err))
+		logger.Error(fmt.Sprintf("Failed to get the network ID: %v", err))
 		os.Exit(0)
 	}
 	msg, err := types.Sender(types.NewLondonSigner(chainID), tx)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to derive the sender address: %v", err))
+		logger.Error(fmt.Sprintf("Failed to derive the sender address: %v", err))
 		os.Exit(0)
 	}
 
 	receipt, err := client.TransactionReceipt(context.Background(), txHash)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to fetch the transaction receipt: %v", err))
+		logger.Error(fmt.Sprintf("Failed to fetch the transaction receipt: %v", err))
 		os.Exit(0)
 	}
 
@@ -93,32 +92,16 @@ func SaveTxns(client *ethclient.Client, ctx context.Context, ldt *leveldb.DB, tr
 
 	if tx.To() == nil {
 		// Contract creation
-		fmt.Printf("Contract created at %s\n", msg.Hex())
+		logger.Debug(fmt.Sprintf("Contract created at %s", msg.Hex()))
 	} else {
-		fmt.Printf("Transaction to: %s\n", tx.To().Hex())
+		logger.Debug(fmt.Sprintf("Transaction to: %s", tx.To().Hex()))
 	}
 
 	var toValue string
 
-	//
-
 	if tx.To() == nil {
 		// Contract creation
 		toValue = msg.Hex()
-		// fmt.Println("blockHash", blockHash)
-		// fmt.Println("blockNumberUint64", blockNumberUint64)
-		// fmt.Println("contract address", msg.Hex())
-		// fmt.Println("Gas", evmcommon.ToString(tx.Gas()))
-		// fmt.Println("GasPrice", tx.GasPrice().String())
-		// fmt.Println("Hash", tx.Hash().Hex())
-		// fmt.Println("Nonce", evmcommon.ToString(tx.Nonce()))
-		// fmt.Println("r", r.String())
-		// fmt.Println("s", s.String())
-		// fmt.Println("toValue", toValue)
-		// fmt.Println("TransactionIndex", evmcommon.ToString(receipt.TransactionIndex))
-		// fmt.Println(fmt.Sprintf("Type: %d", tx.Type()))
-		// fmt.Println("v", v.String())
-		// fmt.Println("Value", tx.Value().String())
 	} else {
 		toValue = tx.To().Hex()
 	}
@@ -143,7 +126,7 @@ func SaveTxns(client *ethclient.Client, ctx context.Context, ldt *leveldb.DB, tr
 
 	fileOpen, err := os.Open("data/transactionCount.txt")
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to read file: %s" + err.Error()))
+		logger.Error(fmt.Sprintf("Failed to read file: %s" + err.Error()))
 		os.Exit(0)
 	}
 	defer fileOpen.Close()
@@ -158,16 +141,17 @@ func SaveTxns(client *ethclient.Client, ctx context.Context, ldt *leveldb.DB, tr
 
 	transactionNumber, err := strconv.Atoi(strings.TrimSpace(string(transactionNumberBytes)))
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Invalid transaction number : %s" + err.Error()))
+		logger.Error(fmt.Sprintf("Invalid transaction number : %s" + err.Error()))
 		os.Exit(0)
 	}
 
 	insetTxnErr := insertTxn(ldt, txData, transactionNumber)
 	if insetTxnErr != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to insert transaction: %s" + insetTxnErr.Error()))
+		logger.Error(fmt.Sprintf("Failed to insert transaction: %s" + insetTxnErr.Error()))
 		os.Exit(0)
 	}
+	metrics.IngestedTransactions.Inc()
 
-	logs.Log.Debug(fmt.Sprintf("Successfully saved Transation %s in the latest phase", txHash))
+	logger.Debug(fmt.Sprintf("Successfully saved Transation %s in the latest phase", txHash))
 
 }
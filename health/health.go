@@ -0,0 +1,284 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/handlers"
+	da_client "github.com/airchains-network/evm-sequencer-node/handlers/da-client"
+	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckResult is the outcome of one check. Detail holds what the check measured.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Detail any    `json:"detail,omitempty"`
+}
+
+// Report is the JSON body of /healthz and /readyz.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// livenessChecks are the checks that fail /healthz; the others only fail /readyz. They
+// cover what a restart could fix, not the availability of other services.
+var livenessChecks = map[string]bool{
+	"databases":    true,
+	"proving_keys": true,
+}
+
+// Checker checks the dependencies of the sequencer and how far it lags behind the chain.
+type Checker struct {
+	client     *ethclient.Client
+	daLayers   *da_client.Failover
+	settlement settlement_client.SettlementClient
+	lds        *leveldb.DB
+	ldbatch    *leveldb.DB
+	dbs        map[string]*leveldb.DB
+	keyFiles   []string
+	started    time.Time
+	checks     map[string]func(ctx context.Context) (any, error)
+}
+
+// NewChecker returns a Checker of the given clients, of the databases in dbs and of the
+// presence of keyFiles.
+func NewChecker(client *ethclient.Client, daLayers *da_client.Failover, settlement settlement_client.SettlementClient, lds *leveldb.DB, ldbatch *leveldb.DB, dbs map[string]*leveldb.DB, keyFiles []string) *Checker {
+	c := &Checker{
+		client:     client,
+		daLayers:   daLayers,
+		settlement: settlement,
+		lds:        lds,
+		ldbatch:    ldbatch,
+		dbs:        dbs,
+		keyFiles:   keyFiles,
+		started:    time.Now(),
+	}
+	c.checks = map[string]func(ctx context.Context) (any, error){
+		"databases":        c.checkDatabases,
+		"proving_keys":     c.checkKeyFiles,
+		"execution_client": c.checkExecutionClient,
+		"da":               c.checkDA,
+		"settlement":       c.checkSettlement,
+		"batch_lag":        c.checkBatchLag,
+	}
+	return c
+}
+
+// Liveness serves /healthz, failing with 503 when a liveness check fails.
+func (c *Checker) Liveness() http.Handler {
+	return c.handler(func(name string) bool { return livenessChecks[name] })
+}
+
+// Readiness serves /readyz, failing with 503 when any check fails.
+func (c *Checker) Readiness() http.Handler {
+	return c.handler(func(string) bool { return true })
+}
+
+func (c *Checker) handler(counts func(name string) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		report.Status = StatusOK
+		for name, result := range report.Checks {
+			if result.Status == StatusFail && counts(name) {
+				report.Status = StatusFail
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Check runs every check, each within HealthCheckTimeout seconds.
+func (c *Checker) Check(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, common.HealthCheckTimeout*time.Second)
+	defer cancel()
+
+	report := &Report{Checks: map[string]CheckResult{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) (any, error)) {
+			defer wg.Done()
+			result := run(ctx, check)
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run returns the result of check, or a failure when it does not finish before ctx is done.
+func run(ctx context.Context, check func(ctx context.Context) (any, error)) CheckResult {
+	done := make(chan CheckResult, 1)
+	go func() {
+		detail, err := check(ctx)
+		if err != nil {
+			done <- CheckResult{Status: StatusFail, Error: err.Error(), Detail: detail}
+			return
+		}
+		done <- CheckResult{Status: StatusOK, Detail: detail}
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return CheckResult{Status: StatusFail, Error: "timed out"}
+	}
+}
+
+func (c *Checker) checkDatabases(ctx context.Context) (any, error) {
+	detail := map[string]string{}
+	var failed []string
+	for name, db := range c.dbs {
+		if _, err := db.Has([]byte("health"), nil); err != nil {
+			detail[name] = err.Error()
+			failed = append(failed, name)
+			continue
+		}
+		detail[name] = StatusOK
+	}
+	if len(failed) > 0 {
+		return detail, fmt.Errorf("databases not readable: %s", strings.Join(failed, ", "))
+	}
+	return detail, nil
+}
+
+func (c *Checker) checkKeyFiles(ctx context.Context) (any, error) {
+	var missing []string
+	for _, keyFile := range c.keyFiles {
+		if _, err := os.Stat(keyFile); err != nil {
+			missing = append(missing, keyFile)
+		}
+	}
+	if len(missing) > 0 {
+		return c.keyFiles, fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return c.keyFiles, nil
+}
+
+// checkExecutionClient compares the last ingested block with the chain head.
+func (c *Checker) checkExecutionClient(ctx context.Context) (any, error) {
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	head := header.Number.Int64()
+
+	// blockCount.txt holds the next block to ingest
+	nextBlock, err := readCount("data/blockCount.txt")
+	if err != nil {
+		return nil, err
+	}
+	ingested := int64(nextBlock) - 1
+
+	detail := map[string]int64{
+		"head":          head,
+		"ingested":      ingested,
+		"blocks_behind": head - ingested,
+		"max_behind":    common.HealthMaxBlockLag,
+	}
+	if head-ingested > common.HealthMaxBlockLag {
+		return detail, fmt.Errorf("ingestion is %d blocks behind the chain head", head-ingested)
+	}
+	return detail, nil
+}
+
+func (c *Checker) checkDA(ctx context.Context) (any, error) {
+	daClient := c.daLayers.Active()
+	detail := map[string]string{"layer": daClient.Name()}
+	return detail, daClient.Status(ctx)
+}
+
+func (c *Checker) checkSettlement(ctx context.Context) (any, error) {
+	settlementChainInfoByte, err := c.lds.Get([]byte("settlementChainInfo"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("station is not registered: %w", err)
+	}
+	var settlementChainInfo types.SettlementLayerChainInfoStruct
+	if err := json.Unmarshal(settlementChainInfoByte, &settlementChainInfo); err != nil {
+		return nil, err
+	}
+
+	latestPod, err := c.settlement.LatestPod(ctx, settlementChainInfo.ChainId)
+	if err != nil {
+		return nil, err
+	}
+	return latestPod, nil
+}
+
+// checkBatchLag fails when transactions have been waiting for more than HealthMaxBatchAge
+// minutes since the last batch was verified. A quiet chain is not lagging.
+func (c *Checker) checkBatchLag(ctx context.Context) (any, error) {
+	batchCount, err := readStaticInt(c.lds, "batchCount")
+	if err != nil {
+		return nil, err
+	}
+	batchedTxs, err := readStaticInt(c.lds, "batchStartIndex")
+	if err != nil {
+		return nil, err
+	}
+	ingestedTxs, err := readCount("data/transactionCount.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	lastBatchTime := c.started
+	if state, err := handlers.GetBatchState(c.ldbatch, batchCount); err == nil && time.Unix(state.UpdatedAt, 0).After(lastBatchTime) {
+		lastBatchTime = time.Unix(state.UpdatedAt, 0)
+	}
+	age := time.Since(lastBatchTime)
+
+	detail := map[string]any{
+		"last_batch":             batchCount,
+		"minutes_since_batch":    int(age.Minutes()),
+		"max_minutes":            common.HealthMaxBatchAge,
+		"transactions_unsettled": ingestedTxs - batchedTxs,
+	}
+	if ingestedTxs > batchedTxs && age > common.HealthMaxBatchAge*time.Minute {
+		return detail, fmt.Errorf("%d transactions are waiting and no batch was verified for %d minutes", ingestedTxs-batchedTxs, int(age.Minutes()))
+	}
+	return detail, nil
+}
+
+func readCount(file string) (int, error) {
+	countBytes, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(countBytes)))
+}
+
+func readStaticInt(lds *leveldb.DB, key string) (int, error) {
+	value, err := lds.Get([]byte(key), nil)
+	if err != nil {
+		return 0, fmt.Errorf("error getting %s from static db: %w", key, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(value)))
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func pass(ctx context.Context) (any, error) { return "detail", nil }
+
+func fail(ctx context.Context) (any, error) { return nil, errors.New("down") }
+
+func TestCheckAggregation(t *testing.T) {
+	tests := []struct {
+		name          string
+		failing       []string
+		wantLiveness  string
+		wantReadiness string
+	}{
+		{name: "all pass", wantLiveness: StatusOK, wantReadiness: StatusOK},
+		{name: "database", failing: []string{"databases"}, wantLiveness: StatusFail, wantReadiness: StatusFail},
+		{name: "proving keys", failing: []string{"proving_keys"}, wantLiveness: StatusFail, wantReadiness: StatusFail},
+		// other services being down does not call for a restart
+		{name: "da", failing: []string{"da"}, wantLiveness: StatusOK, wantReadiness: StatusFail},
+		{name: "settlement and lag", failing: []string{"settlement", "batch_lag"}, wantLiveness: StatusOK, wantReadiness: StatusFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Checker{checks: map[string]func(ctx context.Context) (any, error){}}
+			for _, name := range []string{"databases", "proving_keys", "execution_client", "da", "settlement", "batch_lag"} {
+				c.checks[name] = pass
+			}
+			for _, name := range tt.failing {
+				c.checks[name] = fail
+			}
+
+			for _, endpoint := range []struct {
+				handler http.Handler
+				want    string
+			}{
+				{c.Liveness(), tt.wantLiveness},
+				{c.Readiness(), tt.wantReadiness},
+			} {
+				rec := httptest.NewRecorder()
+				endpoint.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+				var report Report
+				if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+					t.Fatal(err)
+				}
+				wantCode := http.StatusOK
+				if endpoint.want == StatusFail {
+					wantCode = http.StatusServiceUnavailable
+				}
+				if report.Status != endpoint.want || rec.Code != wantCode {
+					t.Fatalf("got %s (%d), want %s (%d)", report.Status, rec.Code, endpoint.want, wantCode)
+				}
+				if len(report.Checks) != len(c.checks) {
+					t.Fatalf("report has %d checks, want %d", len(report.Checks), len(c.checks))
+				}
+				for _, name := range tt.failing {
+					if result := report.Checks[name]; result.Status != StatusFail || result.Error != "down" {
+						t.Fatalf("check %s reported %+v", name, result)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result := run(ctx, func(ctx context.Context) (any, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	})
+	if result.Status != StatusFail || result.Error != "timed out" {
+		t.Fatalf("got %+v", result)
+	}
+}
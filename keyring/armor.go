@@ -0,0 +1,204 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blowfish"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// Cosmos-SDK ASCII armor of a private key: the amino encoded key, encrypted with
+// xsalsa20-poly1305 under the sha256 of a bcrypt hash of the passphrase ("bcrypt" kdf,
+// exported up to v0.46) or with chacha20-poly1305 and a zero nonce under an argon2id hash
+// of the passphrase ("argon2" kdf, exported since v0.47).
+const (
+	armorBlockType = "TENDERMINT PRIVATE KEY"
+	armorKeyType   = "secp256k1"
+	kdfBcrypt      = "bcrypt"
+	kdfArgon2      = "argon2"
+	bcryptCost     = 12
+	argon2Time     = 1
+	argon2Memory   = 64 * 1024
+	argon2Threads  = 4
+)
+
+// aminoSecp256k1Prefix is the amino prefix of tendermint/PrivKeySecp256k1 followed by the
+// length of the key.
+var aminoSecp256k1Prefix = []byte{0xe1, 0xb0, 0xf7, 0x9b, 0x20}
+
+// ExportArmor returns the key encrypted with passphrase as Cosmos-SDK ASCII armor, which
+// can be imported with the import command of Cosmos-SDK chains. It uses the bcrypt kdf,
+// which every Cosmos-SDK version can import.
+func (k *Key) ExportArmor(passphrase string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	secret, err := armorSecret(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return "", err
+	}
+	plaintext := append(append([]byte{}, aminoSecp256k1Prefix...), crypto.FromECDSA(k.privateKey)...)
+	encrypted := secretbox.Seal(nonce[:], plaintext, &nonce, &secret)
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, armorBlockType, map[string]string{
+		"kdf":  kdfBcrypt,
+		"salt": fmt.Sprintf("%X", salt),
+		"type": armorKeyType,
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(encrypted); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// ImportArmor stores the key of a Cosmos-SDK ASCII armor, decrypted with armorPassphrase,
+// in dir as name, encrypted with passphrase.
+func ImportArmor(dir string, name string, armored string, armorPassphrase string, passphrase string) (*Key, error) {
+	block, err := armor.Decode(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding armor: %w", err)
+	}
+	if block.Type != armorBlockType {
+		return nil, fmt.Errorf("unexpected armor type %q", block.Type)
+	}
+	if keyType, ok := block.Header["type"]; ok && keyType != armorKeyType {
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+
+	salt, err := hex.DecodeString(block.Header["salt"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	encrypted, err := io.ReadAll(block.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var plaintext []byte
+	switch block.Header["kdf"] {
+	case kdfBcrypt:
+		plaintext, err = openBcryptArmor(encrypted, armorPassphrase, salt)
+	case kdfArgon2:
+		plaintext, err = openArgon2Armor(encrypted, armorPassphrase, salt)
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", block.Header["kdf"])
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(plaintext, aminoSecp256k1Prefix) || len(plaintext) != len(aminoSecp256k1Prefix)+32 {
+		return nil, fmt.Errorf("armored key is not a secp256k1 key")
+	}
+
+	privateKey, err := crypto.ToECDSA(plaintext[len(aminoSecp256k1Prefix):])
+	if err != nil {
+		return nil, err
+	}
+
+	return store(dir, name, privateKey, passphrase)
+}
+
+// openBcryptArmor decrypts the body of a bcrypt armor: a 24 byte nonce followed by the
+// secretbox of the key.
+func openBcryptArmor(encrypted []byte, passphrase string, salt []byte) ([]byte, error) {
+	if len(encrypted) < 24+secretbox.Overhead {
+		return nil, fmt.Errorf("armored key is too short")
+	}
+
+	secret, err := armorSecret(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], encrypted[:24])
+	plaintext, ok := secretbox.Open(nil, encrypted[24:], &nonce, &secret)
+	if !ok {
+		return nil, fmt.Errorf("invalid passphrase")
+	}
+	return plaintext, nil
+}
+
+// openArgon2Armor decrypts the body of an argon2 armor, sealed with a zero nonce.
+func openArgon2Armor(encrypted []byte, passphrase string, salt []byte) ([]byte, error) {
+	secret := argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, chacha20poly1305.KeySize)
+	aead, err := chacha20poly1305.New(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	plaintext, err := aead.Open(nil, nonce, encrypted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase")
+	}
+	return plaintext, nil
+}
+
+// armorSecret derives the secretbox key of an armor from passphrase and salt as Cosmos-SDK
+// does: the sha256 of the bcrypt hash string built with the given salt.
+func armorSecret(passphrase string, salt []byte) ([32]byte, error) {
+	if len(salt) != 16 {
+		return [32]byte{}, fmt.Errorf("bcrypt salt must be 16 bytes")
+	}
+
+	hash, err := bcryptWithSalt([]byte(passphrase), salt, bcryptCost)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return sha256.Sum256(hash), nil
+}
+
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+// bcryptWithSalt is bcrypt with a caller chosen salt, which golang.org/x/crypto/bcrypt does
+// not allow. It returns the "$2a$" hash string.
+func bcryptWithSalt(password []byte, salt []byte, cost int) ([]byte, error) {
+	// C implementations include the trailing NUL of the password in the key
+	key := append(append([]byte{}, password...), 0)
+
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < 1<<cost; i++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+
+	cipherData := []byte("OrpheanBeholderScryDoubt")
+	for i := 0; i < 24; i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(cipherData[i:i+8], cipherData[i:i+8])
+		}
+	}
+
+	// only 23 of the 24 encrypted bytes are encoded, as in C implementations
+	hash := fmt.Sprintf("$2a$%02d$%s%s", cost, bcryptEncoding.EncodeToString(salt), bcryptEncoding.EncodeToString(cipherData[:23]))
+	return []byte(hash), nil
+}
@@ -0,0 +1,111 @@
+// Package keyring holds the operator keys of the sequencer. Keys are secp256k1 and are
+// stored as scrypt encrypted keystore files, one per key name, in KeyringDirectory.
+package keyring
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase of the operator key.
+const PassphraseEnv = "KEYRING_PASSPHRASE"
+
+// Key is a decrypted operator key.
+type Key struct {
+	Name       string
+	id         uuid.UUID
+	privateKey *ecdsa.PrivateKey
+}
+
+// KeyFile returns the keystore file of the key called name in dir.
+func KeyFile(dir string, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// Load decrypts the key called name from dir with passphrase.
+func Load(dir string, name string, passphrase string) (*Key, error) {
+	keyJSON, err := os.ReadFile(KeyFile(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("error reading key %s: %w", name, err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting key %s: %w", name, err)
+	}
+
+	return &Key{Name: name, id: key.Id, privateKey: key.PrivateKey}, nil
+}
+
+// Address returns the Ethereum address of the key, the address of its keystore file.
+func (k *Key) Address() string {
+	return crypto.PubkeyToAddress(k.privateKey.PublicKey).Hex()
+}
+
+// PubKey returns the 33 byte compressed public key, as used by Cosmos-SDK secp256k1 keys.
+func (k *Key) PubKey() []byte {
+	return crypto.CompressPubkey(&k.privateKey.PublicKey)
+}
+
+// Sign signs the sha256 of msg and returns the 64 byte r || s signature with a low s, the
+// format Cosmos-SDK secp256k1 keys verify.
+func (k *Key) Sign(msg []byte) ([]byte, error) {
+	hash := sha256.Sum256(msg)
+
+	signature, err := crypto.Sign(hash[:], k.privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature[:64], nil
+}
+
+// LoadFromChainInfo decrypts the key named by the key field of chainInfoFile from dir,
+// with the passphrase in PassphraseEnv.
+func LoadFromChainInfo(dir string, chainInfoFile string) (*Key, error) {
+	chainInfoBytes, err := os.ReadFile(chainInfoFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", chainInfoFile, err)
+	}
+
+	var chainInfo types.ChainInfoStruct
+	if err := json.Unmarshal(chainInfoBytes, &chainInfo); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", chainInfoFile, err)
+	}
+
+	return Load(dir, chainInfo.ChainInfo.Key, os.Getenv(PassphraseEnv))
+}
+
+// Verify checks a signature made by Sign against a compressed or uncompressed public key.
+func Verify(pubKey []byte, msg []byte, signature []byte) bool {
+	hash := sha256.Sum256(msg)
+	return crypto.VerifySignature(pubKey, hash[:], signature)
+}
+
+// SignBytes returns the bytes signed for a JSON payload, a settlement request or a DA
+// upload: the payload without pub_key and signature, re-encoded with its keys sorted so the
+// verifier can rebuild it.
+func SignBytes(payloadJSON []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payloadJSON))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "pub_key")
+	delete(fields, "signature")
+
+	return json.Marshal(fields)
+}
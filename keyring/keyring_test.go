@@ -0,0 +1,26 @@
+package keyring
+
+import "testing"
+
+func TestSignBytes(t *testing.T) {
+	// field order, pub_key and signature do not change what is signed; large numbers are
+	// kept as they were sent
+	payloads := []string{
+		`{"station_id":"s","pod_number":18446744073709551615,"merkle_root_hash":"ab"}`,
+		`{"merkle_root_hash":"ab","pod_number":18446744073709551615,"station_id":"s","pub_key":"AQI=","signature":"AwQ="}`,
+	}
+	want := `{"merkle_root_hash":"ab","pod_number":18446744073709551615,"station_id":"s"}`
+	for _, payload := range payloads {
+		got, err := SignBytes([]byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+	if _, err := SignBytes([]byte(`[1]`)); err == nil {
+		t.Error("a payload that is not an object was accepted")
+	}
+}
@@ -0,0 +1,93 @@
+package keyring
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// Create generates a new key called name and stores it in dir.
+func Create(dir string, name string, passphrase string) (*Key, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return store(dir, name, privateKey, passphrase)
+}
+
+// ImportKeystore stores the key of a geth keystore file, decrypted with filePassphrase, in
+// dir as name, encrypted with passphrase.
+func ImportKeystore(dir string, name string, keyJSON []byte, filePassphrase string, passphrase string) (*Key, error) {
+	key, err := keystore.DecryptKey(keyJSON, filePassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting keystore: %w", err)
+	}
+
+	return store(dir, name, key.PrivateKey, passphrase)
+}
+
+// ExportKeystore returns the key encrypted with passphrase as a geth keystore file.
+func (k *Key) ExportKeystore(passphrase string) ([]byte, error) {
+	return keystore.EncryptKey(k.keystoreKey(), passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
+// List returns the names of the keys in dir.
+func List(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Delete removes the key called name from dir.
+func Delete(dir string, name string) error {
+	return os.Remove(KeyFile(dir, name))
+}
+
+func store(dir string, name string, privateKey *ecdsa.PrivateKey, passphrase string) (*Key, error) {
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	if _, err := os.Stat(KeyFile(dir, name)); err == nil {
+		return nil, fmt.Errorf("key %s already exists", name)
+	}
+
+	key := &Key{Name: name, id: uuid.New(), privateKey: privateKey}
+	keyJSON, err := key.ExportKeystore(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(KeyFile(dir, name), keyJSON, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (k *Key) keystoreKey() *keystore.Key {
+	return &keystore.Key{
+		Id:         k.id,
+		Address:    crypto.PubkeyToAddress(k.privateKey.PublicKey),
+		PrivateKey: k.privateKey,
+	}
+}
@@ -5,53 +5,135 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/airchains-network/evm-sequencer-node/admin"
 	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
 	"github.com/airchains-network/evm-sequencer-node/common"
 	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/events"
 	"github.com/airchains-network/evm-sequencer-node/handlers"
+	da_client "github.com/airchains-network/evm-sequencer-node/handlers/da-client"
 	settlement_client "github.com/airchains-network/evm-sequencer-node/handlers/settlement-client"
+	"github.com/airchains-network/evm-sequencer-node/health"
+	"github.com/airchains-network/evm-sequencer-node/keyring"
+	"github.com/airchains-network/evm-sequencer-node/metrics"
 	"github.com/airchains-network/evm-sequencer-node/prover"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
 	"github.com/airchains-network/evm-sequencer-node/types"
+	gnarklogger "github.com/consensys/gnark/logger"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func main() {
+	err := logs.Init(logs.Config{
+		Level:      common.LogLevel,
+		Format:     common.LogFormat,
+		File:       common.LogFile,
+		MaxSizeMB:  common.LogMaxSizeMB,
+		MaxBackups: common.LogMaxBackups,
+		MaxAgeDays: common.LogMaxAgeDays,
+		Console:    common.LogConsole,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	gnarklogger.Set(zerolog.New(logs.Output()).With().Timestamp().Str("component", "gnark").Logger())
+
 	logs.Log.Info("Starting EVM Sequencer")
 
-	err := godotenv.Load()
+	err = godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
-	daClientRPC := os.Getenv("DA_CLIENT_RPC")
-
-	if daClientRPC == "" {
-		logs.Log.Error("Please set all the environment variables")
+	ctx := context.Background()
+	err = tracing.Init(ctx, tracing.Config{
+		ServiceName: "evm-sequencer-node",
+		Exporter:    common.TraceExporter,
+		Endpoint:    common.TraceEndpoint,
+		Insecure:    common.TraceInsecure,
+		File:        common.TraceFile,
+		SampleRatio: common.TraceSampleRatio,
+	})
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in initializing tracing : %s", err.Error()))
 		os.Exit(0)
 	}
+	defer tracing.Shutdown(ctx)
 
-	ctx := context.Background()
-	_ = ctx
 	dbStatus := air.InitDb()
 	if !dbStatus {
 		logs.Log.Error("Error in initializing db")
 		os.Exit(0)
 	}
 
-	prover.CreateVkPk()
-	chainId := settlement_client.AddExecutionLayer()
+	err = prover.CreateVkPk()
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in creating proving and verification keys : %s", err.Error()))
+		os.Exit(0)
+	}
+	var batchProver prover.Prover
+	if common.ProverWorkerRPC != "" {
+		batchProver, err = prover.NewRemoteProver(strings.Split(common.ProverWorkerRPC, ","), common.ProverJobTimeout*time.Second, common.ProverJobRetries, common.ProverWorkerCA, os.Getenv(prover.WorkerTokenEnv))
+	} else {
+		batchProver, err = prover.NewLocalProver(common.ProverBackend, common.ProverCurve, prover.ProvingKeyFile(common.ProverBackend, common.ProverCurve), common.ProvingKeyMmap)
+	}
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in loading prover : %s", err.Error()))
+		os.Exit(0)
+	}
+
+	var aggregator *prover.Aggregator
+	if common.AggregationSize > 0 {
+		if common.ProverBackend != prover.BackendGroth16 {
+			logs.Log.Error(fmt.Sprintf("Aggregation needs the %s backend, ProverBackend is %s", prover.BackendGroth16, common.ProverBackend))
+			os.Exit(0)
+		}
+		aggregator, err = prover.NewAggregator(common.ProverCurve, common.ProvingKeyMmap)
+		if err != nil {
+			logs.Log.Error(fmt.Sprintf("Error in loading aggregator : %s", err.Error()))
+			os.Exit(0)
+		}
+	}
+
+	var operatorKey *keyring.Key
+	if common.SignSettlementRequests {
+		operatorKey, err = keyring.LoadFromChainInfo(common.KeyringDirectory, "config/chainInfo.json")
+		if err != nil {
+			logs.Log.Warn(fmt.Sprintf("Error in loading operator key : %s, settlement requests and DA uploads are sent unsigned until a key is imported with cmd/keys", err.Error()))
+			operatorKey = nil
+		}
+	}
+
+	daLayers, err := da_client.NewFromChainInfo("config/chainInfo.json", operatorKey)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in loading DA client : %s", err.Error()))
+		os.Exit(0)
+	}
+
+	settlement := settlement_client.NewHTTPClient(common.SettlementClientRPC, operatorKey)
+	chainId := settlement.AddExecutionLayer(ctx)
 	if chainId == "nil" {
 		logs.Log.Error("Something went wrong while adding execution layer")
 		logs.Log.Warn("Retrying in 5 seconds...")
 		time.Sleep(5 * time.Second)
-		_ = settlement_client.AddExecutionLayer()
+		_ = settlement.AddExecutionLayer(ctx)
 	} else if chainId == "exist" {
 		logs.Log.Info("Chain already exist")
+		err = settlement_client.CheckRegisteredCircuit(air.GetStaticDbInstance())
+		if err != nil {
+			logs.Log.Error(err.Error())
+			os.Exit(0)
+		}
 	}
 
 	ldt := air.GetTxDbInstance()
@@ -60,6 +142,19 @@ func main() {
 	ldbatch := air.GetBatchesDbInstance()
 	ldda := air.GetDaDbInstance()
 
+	dbs := map[string]*leveldb.DB{
+		"tx":            ldt,
+		"blocks":        ldb,
+		"static":        lds,
+		"batches":       ldbatch,
+		"da":            ldda,
+		"proof":         air.GetProofDbInstance(),
+		"publicWitness": air.GetPublicWitnessDbInstance(),
+	}
+	for name, db := range dbs {
+		metrics.RegisterLevelDB(name, db)
+	}
+
 	da := types.DAStruct{
 		DAKey:             "0",
 		DAClientName:      "0",
@@ -83,7 +178,7 @@ func main() {
 		}
 	}
 
-	batchStartIndex, err := lds.Get([]byte("batchStartIndex"), nil)
+	_, err = lds.Get([]byte("batchStartIndex"), nil)
 	if err != nil {
 		err = lds.Put([]byte("batchStartIndex"), []byte("0"), nil)
 		if err != nil {
@@ -92,10 +187,47 @@ func main() {
 		}
 	}
 
-	client, err := ethclient.Dial(common.ExecutionClientRPC)
+	rpcClient, err := rpc.DialOptions(ctx, common.ExecutionClientRPC, rpc.WithHTTPClient(metrics.RPCClient))
 	if err != nil {
 		log.Fatal("Failed to connect to the Ethereum client:", err)
 	}
+	client := ethclient.NewClient(rpcClient)
+
+	keyFiles := []string{
+		prover.ProvingKeyFile(common.ProverBackend, common.ProverCurve),
+		prover.VerificationKeyFile(common.ProverBackend, common.ProverCurve),
+	}
+	if aggregator != nil {
+		keyFiles = append(keyFiles, prover.AggregationProvingKeyFile(common.ProverCurve), prover.AggregationVerificationKeyFile(common.ProverCurve))
+	}
+	checker := health.NewChecker(client, daLayers, settlement, lds, ldbatch, dbs, keyFiles)
+
+	err = events.StartWebhooks(ctx, events.Default)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in starting webhooks : %s", err.Error()))
+		os.Exit(0)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", checker.Liveness())
+	mux.Handle("/readyz", checker.Readiness())
+	mux.Handle("/events", events.Handler(events.Default))
+	go func() {
+		err := http.ListenAndServe(common.NodeHTTPAddress, mux)
+		logs.Log.Error(fmt.Sprintf("Error in serving %s : %s", common.NodeHTTPAddress, err.Error()))
+	}()
+
+	err = admin.Start(admin.Config{
+		Address:  common.AdminHTTPAddress,
+		TLSCert:  common.AdminTLSCert,
+		TLSKey:   common.AdminTLSKey,
+		ClientCA: common.AdminClientCA,
+	}, handlers.Control)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in starting admin API : %s", err.Error()))
+		os.Exit(0)
+	}
 
 	_, err = lds.Get([]byte("batchCount"), nil)
 	if err != nil {
@@ -106,16 +238,30 @@ func main() {
 		}
 	}
 
+	report, err := handlers.ReconcileWithSettlement(ctx, settlement, lds, ldbatch, ldda, aggregator)
+	if report != nil {
+		reportJSON, _ := json.Marshal(report)
+		logs.Log.Info(fmt.Sprintf("Settlement reconciliation : %s", reportJSON))
+	}
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Local state does not match the settlement layer, halting : %s", err.Error()))
+		os.Exit(0)
+	}
+
 	var wg sync.WaitGroup
 
-	wg.Add(2)
+	wg.Add(3)
 	go func() {
 		defer wg.Done()
 		handlers.BlockCheck(ctx, client, ldb, ldt)
 	}()
 	go func() {
 		defer wg.Done()
-		handlers.BatchGeneration(client, ctx, lds, ldt, ldbatch, ldda, batchStartIndex)
+		handlers.WatchChainHead(ctx, client)
+	}()
+	go func() {
+		defer wg.Done()
+		handlers.BatchGeneration(client, ctx, lds, ldt, ldbatch, ldda, batchProver, aggregator, daLayers, settlement)
 	}()
 	wg.Wait()
 }
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var levelDBSizes = &levelDBCollector{
+	dbs: map[string]*leveldb.DB{},
+	desc: prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "leveldb", "size_bytes"),
+		"Size of the tables of each LevelDB database.",
+		[]string{"db"}, nil,
+	),
+}
+
+// RegisterLevelDB reports the size of db under name.
+func RegisterLevelDB(name string, db *leveldb.DB) {
+	levelDBSizes.mu.Lock()
+	defer levelDBSizes.mu.Unlock()
+	levelDBSizes.dbs[name] = db
+}
+
+// levelDBCollector reads the size of the registered databases when they are scraped.
+type levelDBCollector struct {
+	mu   sync.Mutex
+	dbs  map[string]*leveldb.DB
+	desc *prometheus.Desc
+}
+
+func (c *levelDBCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *levelDBCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stats leveldb.DBStats
+	for name, db := range c.dbs {
+		if err := db.Stats(&stats); err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(stats.LevelSizes.Sum()), name)
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sequencer"
+
+var (
+	IngestedBlock = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ingested_block",
+		Help:      "Number of the last block saved from the execution client.",
+	})
+	ChainHeadBlock = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_head_block",
+		Help:      "Number of the latest block of the execution client.",
+	})
+	IngestedTransactions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingested_transactions_total",
+		Help:      "Transactions saved from the execution client.",
+	})
+	BatchStage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "batch_stage",
+		Help:      "Number of the last batch that entered each lifecycle stage.",
+	}, []string{"stage"})
+	ProvingDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "proving_duration_seconds",
+		Help:      "Time taken to prove a batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+	DARequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "da_request_duration_seconds",
+		Help:      "Latency of DA layer requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"layer", "operation"})
+	DAErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "da_errors_total",
+		Help:      "Failed DA layer requests.",
+	}, []string{"layer", "operation"})
+	SettlementRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "settlement_request_duration_seconds",
+		Help:      "Latency of settlement layer requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"endpoint"})
+	SettlementErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "settlement_errors_total",
+		Help:      "Settlement layer requests that failed or were answered with an HTTP error.",
+	}, []string{"endpoint"})
+	RPCCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_calls_total",
+		Help:      "JSON-RPC calls made to the execution client.",
+	}, []string{"method"})
+	EventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Lifecycle events published, by type.",
+	}, []string{"type"})
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts that delivered, will be retried or dropped an event.",
+	}, []string{"result"})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		IngestedBlock,
+		ChainHeadBlock,
+		IngestedTransactions,
+		BatchStage,
+		ProvingDuration,
+		DARequestDuration,
+		DAErrors,
+		SettlementRequestDuration,
+		SettlementErrors,
+		RPCCalls,
+		EventsPublished,
+		WebhookDeliveries,
+		levelDBSizes,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveDA records a request to a DA layer that started at start.
+func ObserveDA(layer string, operation string, start time.Time, err error) {
+	DARequestDuration.WithLabelValues(layer, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		DAErrors.WithLabelValues(layer, operation).Inc()
+	}
+}
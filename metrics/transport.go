@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RPCClient is the HTTP client for the execution client. It counts the JSON-RPC methods
+// of every request, single or batched.
+var RPCClient = &http.Client{Transport: &rpcTransport{base: http.DefaultTransport}}
+
+// SettlementClient is the HTTP client for the settlement layer. It records the latency of
+// every request and the requests that fail or are answered with an HTTP error, by path.
+var SettlementClient = &http.Client{Transport: &settlementTransport{base: http.DefaultTransport}}
+
+type rpcTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		for _, method := range rpcMethods(body) {
+			RPCCalls.WithLabelValues(method).Inc()
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// rpcMethods returns the methods called by a JSON-RPC request body.
+func rpcMethods(body []byte) []string {
+	type call struct {
+		Method string `json:"method"`
+	}
+
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("[")) {
+		var calls []call
+		if json.Unmarshal(body, &calls) != nil {
+			return nil
+		}
+		methods := make([]string, 0, len(calls))
+		for _, c := range calls {
+			methods = append(methods, c.Method)
+		}
+		return methods
+	}
+
+	var c call
+	if json.Unmarshal(body, &c) != nil || c.Method == "" {
+		return nil
+	}
+	return []string{c.Method}
+}
+
+type settlementTransport struct {
+	base http.RoundTripper
+}
+
+func (t *settlementTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := strings.TrimPrefix(req.URL.Path, "/")
+	start := time.Now()
+
+	resp, err := t.base.RoundTrip(req)
+
+	SettlementRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		SettlementErrors.WithLabelValues(endpoint).Inc()
+	}
+
+	return resp, err
+}
@@ -0,0 +1,459 @@
+package prover
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/types"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+)
+
+// AggregationCircuit recursively verifies common.AggregationSize consecutive Groth16 batch
+// proofs against the station verification key, which is compiled into the circuit as a
+// constant, and exposes the first batch number and the state roots of the covered batches
+// as public inputs. A state root is a 256 bit hash, split into its high and low 128 bits to
+// fit the BN254 field. Each of them is constrained to the public inputs of the proof of its
+// batch, so a proof only verifies for the batch and roots it was made for.
+type AggregationCircuit[S algebra.ScalarT, G1El algebra.G1ElementT, G2El algebra.G2ElementT, GtEl algebra.GtElementT] struct {
+	Proofs             [common.AggregationSize]stdgroth16.Proof[G1El, G2El]
+	Witnesses          [common.AggregationSize]stdgroth16.Witness[S]
+	FirstBatch         frontend.Variable                            `gnark:",public"`
+	PreviousStateRoots [common.AggregationSize][2]frontend.Variable `gnark:",public"`
+	StateRoots         [common.AggregationSize][2]frontend.Variable `gnark:",public"`
+	VerifyingKey       stdgroth16.VerifyingKey[G1El, G2El, GtEl]    `gnark:"-"`
+}
+
+func (circuit *AggregationCircuit[S, G1El, G2El, GtEl]) Define(api frontend.API) error {
+	curve, pairing, err := recursionCurve[S, G1El, G2El, GtEl](api)
+	if err != nil {
+		return err
+	}
+	verifier := stdgroth16.NewVerifier(curve, pairing)
+
+	for i := 0; i < common.AggregationSize; i++ {
+		err := verifier.AssertProof(circuit.VerifyingKey, circuit.Proofs[i], circuit.Witnesses[i])
+		if err != nil {
+			return err
+		}
+
+		// the batch number and roots are the first public inputs of MyCircuit
+		public := circuit.Witnesses[i].Public
+		if len(public) < 5 {
+			return fmt.Errorf("batch proof has %d public inputs, expected at least 5", len(public))
+		}
+		if err := assertScalarEqual(api, &public[0], api.Add(circuit.FirstBatch, i), 64); err != nil {
+			return err
+		}
+		for j := 0; j < 2; j++ {
+			if err := assertScalarEqual(api, &public[1+j], circuit.PreviousStateRoots[i][j], 128); err != nil {
+				return err
+			}
+			if err := assertScalarEqual(api, &public[3+j], circuit.StateRoots[i][j], 128); err != nil {
+				return err
+			}
+		}
+
+		// each batch starts from the root the previous batch ended on
+		if i > 0 {
+			api.AssertIsEqual(circuit.PreviousStateRoots[i][0], circuit.StateRoots[i-1][0])
+			api.AssertIsEqual(circuit.PreviousStateRoots[i][1], circuit.StateRoots[i-1][1])
+		}
+	}
+
+	return nil
+}
+
+// assertScalarEqual asserts that the emulated scalar s of a batch proof equals the native
+// variable v of at most bits bits. bits stays below the size of both scalar fields, so the
+// decomposition of v is the canonical value of s.
+func assertScalarEqual[S algebra.ScalarT](api frontend.API, s *S, v frontend.Variable, bits int) error {
+	switch scalar := any(s).(type) {
+	case *emulated.Element[emulated.BLS12381Fr]:
+		f, err := emulated.NewField[emulated.BLS12381Fr](api)
+		if err != nil {
+			return err
+		}
+		f.AssertIsEqual(scalar, f.FromBits(api.ToBinary(v, bits)...))
+	case *emulated.Element[emulated.BN254Fr]:
+		f, err := emulated.NewField[emulated.BN254Fr](api)
+		if err != nil {
+			return err
+		}
+		f.AssertIsEqual(scalar, f.FromBits(api.ToBinary(v, bits)...))
+	default:
+		return fmt.Errorf("unsupported batch proof scalar %T", s)
+	}
+	return nil
+}
+
+// recursionCurve returns the emulated curve and pairing of the batch proofs. gnark only
+// provides defaults for BN254, so the BLS12-381 ones are built here.
+func recursionCurve[S algebra.ScalarT, G1El algebra.G1ElementT, G2El algebra.G2ElementT, GtEl algebra.GtElementT](api frontend.API) (algebra.Curve[S, G1El], algebra.Pairing[G1El, G2El, GtEl], error) {
+	var curve algebra.Curve[S, G1El]
+	var pairing algebra.Pairing[G1El, G2El, GtEl]
+
+	switch c := any(&curve).(type) {
+	case *algebra.Curve[sw_bls12381.Scalar, sw_bls12381.G1Affine]:
+		bls12381Curve, err := sw_emulated.New[emulated.BLS12381Fp, emulated.BLS12381Fr](api, sw_emulated.GetBLS12381Params())
+		if err != nil {
+			return nil, nil, err
+		}
+		*c = bls12381Curve
+	default:
+		defaultCurve, err := algebra.GetCurve[S, G1El](api)
+		if err != nil {
+			return nil, nil, err
+		}
+		curve = defaultCurve
+	}
+
+	switch p := any(&pairing).(type) {
+	case *algebra.Pairing[sw_bls12381.G1Affine, sw_bls12381.G2Affine, sw_bls12381.GTEl]:
+		bls12381Pairing, err := sw_bls12381.NewPairing(api)
+		if err != nil {
+			return nil, nil, err
+		}
+		*p = bls12381Pairing
+	default:
+		defaultPairing, err := algebra.GetPairing[G1El, G2El, GtEl](api)
+		if err != nil {
+			return nil, nil, err
+		}
+		pairing = defaultPairing
+	}
+
+	return curve, pairing, nil
+}
+
+// AggregationResult is the single proof covering batches FirstBatch to LastBatch.
+type AggregationResult struct {
+	FirstBatch        int             `json:"first_batch"`
+	LastBatch         int             `json:"last_batch"`
+	PreviousStateRoot string          `json:"previous_state_root"`
+	StateRoots        []string        `json:"state_roots"`
+	Proof             json.RawMessage `json:"proof"`
+	PublicWitness     []byte          `json:"public_witness"`
+}
+
+// Aggregator proves AggregationCircuit over BN254 for the batch proofs of the station.
+type Aggregator struct {
+	size       int
+	innerCurve ecc.ID
+	ccs        constraint.ConstraintSystem
+	pk         groth16.ProvingKey
+	assign     func(firstBatch int, proofs []groth16.Proof, witnesses []witness.Witness, previousRoots, roots [][2]frontend.Variable) (frontend.Circuit, error)
+}
+
+// AggregationProvingKeyFile returns the proving key file of the aggregation circuit for
+// batch proofs over curve.
+func AggregationProvingKeyFile(curve string) string {
+	return fmt.Sprintf("aggregationProvingKey%s.txt", keyFileTag(BackendGroth16, curve))
+}
+
+// AggregationVerificationKeyFile returns the verification key file of the aggregation
+// circuit for batch proofs over curve.
+func AggregationVerificationKeyFile(curve string) string {
+	return fmt.Sprintf("aggregationVerificationKey%s.json", keyFileTag(BackendGroth16, curve))
+}
+
+// NewAggregator compiles the aggregation circuit for Groth16 batch proofs over curve and
+// loads its proving key, running the setup first when the keys do not exist yet.
+func NewAggregator(curve string, useMmap bool) (*Aggregator, error) {
+	innerCurve, err := curveID(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	innerCcs, err := compileCircuit(BackendGroth16, curve)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling batch circuit: %w", err)
+	}
+
+	innerVkBytes, err := os.ReadFile(VerificationKeyFile(BackendGroth16, curve))
+	if err != nil {
+		return nil, fmt.Errorf("error reading batch verification key: %w", err)
+	}
+	innerVk := groth16.NewVerifyingKey(innerCurve)
+	if err := json.Unmarshal(innerVkBytes, innerVk); err != nil {
+		return nil, fmt.Errorf("error decoding batch verification key: %w", err)
+	}
+
+	a := &Aggregator{size: common.AggregationSize, innerCurve: innerCurve}
+	var circuit frontend.Circuit
+	switch innerCurve {
+	case ecc.BLS12_381:
+		circuit, err = newAggregationCircuit[sw_bls12381.Scalar, sw_bls12381.G1Affine, sw_bls12381.G2Affine, sw_bls12381.GTEl](innerCcs, innerVk)
+		a.assign = assignAggregationCircuit[sw_bls12381.Scalar, sw_bls12381.G1Affine, sw_bls12381.G2Affine, sw_bls12381.GTEl]
+	case ecc.BN254:
+		circuit, err = newAggregationCircuit[sw_bn254.Scalar, sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl](innerCcs, innerVk)
+		a.assign = assignAggregationCircuit[sw_bn254.Scalar, sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.ccs, err = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling aggregation circuit: %w", err)
+	}
+
+	provingKeyFile := AggregationProvingKeyFile(curve)
+	if _, err := os.Stat(provingKeyFile); os.IsNotExist(err) {
+		if err := a.setup(curve); err != nil {
+			return nil, err
+		}
+	}
+
+	pk, err := readProvingKey(BackendGroth16, ecc.BN254, provingKeyFile, useMmap)
+	if err != nil {
+		return nil, fmt.Errorf("error reading aggregation proving key: %w", err)
+	}
+	if err := validateProvingKey(a.ccs, pk); err != nil {
+		return nil, err
+	}
+	a.pk = pk.(groth16.ProvingKey)
+
+	return a, nil
+}
+
+// setup runs the Groth16 setup of the aggregation circuit and writes its keys, in the same
+// way CreateVkPk does for the batch circuit.
+func (a *Aggregator) setup(curve string) error {
+	logs.Log.Info("Generating aggregation proving and verification keys")
+	pk, vk, err := groth16.Setup(a.ccs)
+	if err != nil {
+		return fmt.Errorf("error in aggregation setup: %w", err)
+	}
+
+	vkJSON, err := json.Marshal(vk)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(AggregationVerificationKeyFile(curve), vkJSON, 0644); err != nil {
+		return fmt.Errorf("error writing aggregation verification key: %w", err)
+	}
+
+	file, err := os.Create(AggregationProvingKeyFile(curve))
+	if err != nil {
+		return fmt.Errorf("error creating aggregation proving key file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := pk.WriteTo(file); err != nil {
+		return fmt.Errorf("error writing aggregation proving key: %w", err)
+	}
+
+	return nil
+}
+
+func newAggregationCircuit[S algebra.ScalarT, G1El algebra.G1ElementT, G2El algebra.G2ElementT, GtEl algebra.GtElementT](innerCcs constraint.ConstraintSystem, innerVk groth16.VerifyingKey) (frontend.Circuit, error) {
+	verifyingKey, err := stdgroth16.ValueOfVerifyingKey[G1El, G2El, GtEl](innerVk)
+	if err != nil {
+		return nil, err
+	}
+
+	circuit := &AggregationCircuit[S, G1El, G2El, GtEl]{VerifyingKey: verifyingKey}
+	for i := range circuit.Witnesses {
+		circuit.Witnesses[i] = stdgroth16.PlaceholderWitness[S](innerCcs)
+	}
+
+	return circuit, nil
+}
+
+func assignAggregationCircuit[S algebra.ScalarT, G1El algebra.G1ElementT, G2El algebra.G2ElementT, GtEl algebra.GtElementT](firstBatch int, proofs []groth16.Proof, witnesses []witness.Witness, previousRoots, roots [][2]frontend.Variable) (frontend.Circuit, error) {
+	assignment := &AggregationCircuit[S, G1El, G2El, GtEl]{FirstBatch: firstBatch}
+	for i := 0; i < common.AggregationSize; i++ {
+		proof, err := stdgroth16.ValueOfProof[G1El, G2El](proofs[i])
+		if err != nil {
+			return nil, err
+		}
+		publicWitness, err := stdgroth16.ValueOfWitness[S, G1El](witnesses[i])
+		if err != nil {
+			return nil, err
+		}
+		assignment.Proofs[i] = proof
+		assignment.Witnesses[i] = publicWitness
+		assignment.PreviousStateRoots[i] = previousRoots[i]
+		assignment.StateRoots[i] = roots[i]
+	}
+
+	return assignment, nil
+}
+
+// Size is the number of batches covered by one aggregation proof.
+func (a *Aggregator) Size() int {
+	return a.size
+}
+
+// EndsRange reports whether batchNum is the last batch of an aggregation range.
+func (a *Aggregator) EndsRange(batchNum int) bool {
+	return a.size > 0 && batchNum%a.size == 0
+}
+
+// Aggregate proves the common.AggregationSize batches starting at firstBatch in a single
+// proof and stores it in the proof db under "aggregate_proof_<first>_<last>".
+func (a *Aggregator) Aggregate(firstBatch int) (*AggregationResult, error) {
+	lastBatch := firstBatch + a.size - 1
+	result := &AggregationResult{FirstBatch: firstBatch, LastBatch: lastBatch}
+
+	var proofs []groth16.Proof
+	var witnesses []witness.Witness
+	var previousRoots, roots [][2]frontend.Variable
+	for batchNum := firstBatch; batchNum <= lastBatch; batchNum++ {
+		if backend := ProofBackend(batchNum); backend != BackendGroth16 {
+			return nil, fmt.Errorf("batch %d was proved with %s, only %s proofs can be aggregated", batchNum, backend, BackendGroth16)
+		}
+		if curve := ProofCurve(batchNum); curve != a.innerCurve.String() {
+			return nil, fmt.Errorf("batch %d was proved over %s, the aggregator expects %s", batchNum, curve, a.innerCurve)
+		}
+
+		proofBytes, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_%d", batchNum)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error getting proof of batch %d: %w", batchNum, err)
+		}
+		proof := groth16.NewProof(a.innerCurve)
+		if err := json.Unmarshal(proofBytes, proof); err != nil {
+			return nil, fmt.Errorf("error decoding proof of batch %d: %w", batchNum, err)
+		}
+
+		publicWitnessBytes, err := air.GetPublicWitnessDbInstance().Get([]byte(fmt.Sprintf("public_witness_%d", batchNum)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error getting public witness of batch %d: %w", batchNum, err)
+		}
+		publicWitness, err := witness.New(a.innerCurve.ScalarField())
+		if err != nil {
+			return nil, err
+		}
+		if err := publicWitness.UnmarshalBinary(publicWitnessBytes); err != nil {
+			return nil, fmt.Errorf("error decoding public witness of batch %d: %w", batchNum, err)
+		}
+
+		daBytes, err := air.GetDaDbInstance().Get([]byte(fmt.Sprintf("batch_%d", batchNum)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error getting da of batch %d: %w", batchNum, err)
+		}
+		var da types.DAStruct
+		if err := json.Unmarshal(daBytes, &da); err != nil {
+			return nil, fmt.Errorf("error decoding da of batch %d: %w", batchNum, err)
+		}
+
+		if batchNum == firstBatch {
+			result.PreviousStateRoot = da.PreviousStateHash
+		} else if da.PreviousStateHash != result.StateRoots[len(result.StateRoots)-1] {
+			return nil, fmt.Errorf("batch %d does not start from the state root of batch %d", batchNum, batchNum-1)
+		}
+
+		previousRoot, err := stateRootLimbs(da.PreviousStateHash)
+		if err != nil {
+			return nil, err
+		}
+		root, err := stateRootLimbs(da.CurrentStateHash)
+		if err != nil {
+			return nil, err
+		}
+
+		proofs = append(proofs, proof)
+		witnesses = append(witnesses, publicWitness)
+		previousRoots = append(previousRoots, previousRoot)
+		roots = append(roots, root)
+		result.StateRoots = append(result.StateRoots, da.CurrentStateHash)
+	}
+
+	assignment, err := a.assign(firstBatch, proofs, witnesses, previousRoots, roots)
+	if err != nil {
+		return nil, err
+	}
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("error creating aggregation witness: %w", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := groth16.Prove(a.ccs, a.pk, fullWitness)
+	if err != nil {
+		return nil, fmt.Errorf("error generating aggregation proof: %w", err)
+	}
+
+	result.Proof, err = json.Marshal(proof)
+	if err != nil {
+		return nil, err
+	}
+	result.PublicWitness, err = publicWitness.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	proofDb := air.GetProofDbInstance()
+	err = proofDb.Put([]byte(fmt.Sprintf("aggregate_proof_%d_%d", firstBatch, lastBatch)), result.Proof, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error saving aggregation proof: %w", err)
+	}
+	err = air.GetPublicWitnessDbInstance().Put([]byte(fmt.Sprintf("aggregate_public_witness_%d_%d", firstBatch, lastBatch)), result.PublicWitness, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error saving aggregation public witness: %w", err)
+	}
+
+	return result, nil
+}
+
+// AggregationPublicWitness builds the public witness of the aggregation proof of the batches
+// from firstBatch on, going from previousStateRoot through stateRoots. It is how the
+// settlement layer rebuilds the statement of a pod range instead of trusting the witness
+// sent with it.
+func AggregationPublicWitness(firstBatch int, previousStateRoot string, stateRoots []string) (witness.Witness, error) {
+	inputs := []*big.Int{big.NewInt(int64(firstBatch))}
+	var previousRoots, roots []*big.Int
+	for i, stateRoot := range stateRoots {
+		if i > 0 {
+			previousStateRoot = stateRoots[i-1]
+		}
+		previousRoot, err := stateRootLimbs(previousStateRoot)
+		if err != nil {
+			return nil, err
+		}
+		root, err := stateRootLimbs(stateRoot)
+		if err != nil {
+			return nil, err
+		}
+		previousRoots = append(previousRoots, previousRoot[0].(*big.Int), previousRoot[1].(*big.Int))
+		roots = append(roots, root[0].(*big.Int), root[1].(*big.Int))
+	}
+	inputs = append(inputs, previousRoots...)
+	inputs = append(inputs, roots...)
+
+	return fillWitness(ecc.BN254.ScalarField(), inputs)
+}
+
+// stateRootLimbs splits a hex state root into its high and low 128 bits.
+func stateRootLimbs(stateRoot string) ([2]frontend.Variable, error) {
+	root, ok := new(big.Int).SetString(stateRoot, 16)
+	if !ok {
+		return [2]frontend.Variable{}, fmt.Errorf("invalid state root %q", stateRoot)
+	}
+
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	high := new(big.Int).Rsh(root, 128)
+	low := new(big.Int).And(root, mask)
+
+	return [2]frontend.Variable{high, low}, nil
+}
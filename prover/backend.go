@@ -0,0 +1,163 @@
+package prover
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	gnarkio "github.com/consensys/gnark/io"
+)
+
+// Proving backends selectable with common.ProverBackend.
+const (
+	BackendGroth16 = "groth16"
+	BackendPlonk   = "plonk"
+)
+
+// Curves selectable with common.ProverCurve, named as ecc.ID.String(). BN254 proofs can
+// be verified on Ethereum through the pairing precompiles.
+const (
+	CurveBLS12_381 = "bls12_381"
+	CurveBN254     = "bn254"
+)
+
+// provingKey is implemented by both groth16.ProvingKey and plonk.ProvingKey.
+type provingKey interface {
+	io.ReaderFrom
+	gnarkio.UnsafeReaderFrom
+}
+
+// curveID returns the pairing curve of a curve name.
+func curveID(curve string) (ecc.ID, error) {
+	switch curve {
+	case CurveBLS12_381:
+		return ecc.BLS12_381, nil
+	case CurveBN254:
+		return ecc.BN254, nil
+	default:
+		return ecc.UNKNOWN, fmt.Errorf("unknown prover curve %q", curve)
+	}
+}
+
+// curveFromField returns the pairing curve whose scalar field is field, which is how the
+// circuit finds out which curve it is compiled for.
+func curveFromField(field *big.Int) (ecc.ID, error) {
+	for _, id := range []ecc.ID{ecc.BLS12_381, ecc.BN254} {
+		if id.ScalarField().Cmp(field) == 0 {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unsupported circuit field %s", field)
+}
+
+// twistedEdwardsID returns the twisted Edwards curve embedded in the scalar field of id,
+// used for the EdDSA signatures of the circuit.
+func twistedEdwardsID(id ecc.ID) tedwards.ID {
+	if id == ecc.BN254 {
+		return tedwards.BN254
+	}
+	return tedwards.BLS12_381
+}
+
+// mimcHash returns the MiMC hash over the scalar field of id.
+func mimcHash(id ecc.ID) hash.Hash {
+	if id == ecc.BN254 {
+		return hash.MIMC_BN254
+	}
+	return hash.MIMC_BLS12_381
+}
+
+// CircuitVersion is the version of MyCircuit. Version 2 added the BatchNumber,
+// PreviousStateRoot and StateRoot public inputs; keys from the setup of an older version
+// do not prove or verify it.
+const CircuitVersion = 2
+
+// keyFileTag tags key files with the circuit version, and with the backend and curve unless
+// they are Groth16 over BLS12-381. Keys of version 1 carry no version tag and are left
+// alone, so a station upgrading runs a new setup instead of loading them.
+func keyFileTag(backend string, curve string) string {
+	tags := []string{fmt.Sprintf("v%d", CircuitVersion)}
+	if backend != BackendGroth16 {
+		tags = append(tags, backend)
+	}
+	if curve != CurveBLS12_381 {
+		tags = append(tags, curve)
+	}
+	return "." + strings.Join(tags, ".")
+}
+
+// ProvingKeyFile returns the proving key file of a backend and curve.
+func ProvingKeyFile(backend string, curve string) string {
+	return fmt.Sprintf("provingKey%s.txt", keyFileTag(backend, curve))
+}
+
+// VerificationKeyFile returns the verification key file of a backend and curve.
+func VerificationKeyFile(backend string, curve string) string {
+	return fmt.Sprintf("verificationKey%s.json", keyFileTag(backend, curve))
+}
+
+// compileCircuit compiles the batch circuit over curve with the constraint system builder
+// of the backend: R1CS for Groth16 and SparseR1CS for PLONK.
+func compileCircuit(backend string, curve string) (constraint.ConstraintSystem, error) {
+	id, err := curveID(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	var circuit MyCircuit
+	switch backend {
+	case BackendGroth16:
+		return frontend.Compile(id.ScalarField(), r1cs.NewBuilder, &circuit)
+	case BackendPlonk:
+		return frontend.Compile(id.ScalarField(), scs.NewBuilder, &circuit)
+	default:
+		return nil, fmt.Errorf("unknown prover backend %q", backend)
+	}
+}
+
+func newProvingKey(backend string, id ecc.ID) (provingKey, error) {
+	switch backend {
+	case BackendGroth16:
+		return groth16.NewProvingKey(id), nil
+	case BackendPlonk:
+		return plonk.NewProvingKey(id), nil
+	default:
+		return nil, fmt.Errorf("unknown prover backend %q", backend)
+	}
+}
+
+// ReadKzgSrsFromFile loads the universal KZG SRS over curve used by the PLONK setup. The
+// SRS must be at least as large as the compiled circuit and should come from a public
+// ceremony.
+func ReadKzgSrsFromFile(filename string, curve string) (kzg.SRS, error) {
+	id, err := curveID(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	srs := kzg.NewSRS(id)
+	_, err = srs.ReadFrom(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return srs, nil
+}
@@ -0,0 +1,31 @@
+package prover
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKeyFiles(t *testing.T) {
+	tests := []struct {
+		backend         string
+		curve           string
+		provingKey      string
+		verificationKey string
+	}{
+		{BackendGroth16, CurveBLS12_381, "provingKey.v%d.txt", "verificationKey.v%d.json"},
+		{BackendGroth16, CurveBN254, "provingKey.v%d.bn254.txt", "verificationKey.v%d.bn254.json"},
+		{BackendPlonk, CurveBLS12_381, "provingKey.v%d.plonk.txt", "verificationKey.v%d.plonk.json"},
+		{BackendPlonk, CurveBN254, "provingKey.v%d.plonk.bn254.txt", "verificationKey.v%d.plonk.bn254.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend+"/"+tt.curve, func(t *testing.T) {
+			// keys of the first circuit were untagged and must never be loaded for this one
+			if got, want := ProvingKeyFile(tt.backend, tt.curve), fmt.Sprintf(tt.provingKey, CircuitVersion); got != want {
+				t.Errorf("proving key file is %s, want %s", got, want)
+			}
+			if got, want := VerificationKeyFile(tt.backend, tt.curve), fmt.Sprintf(tt.verificationKey, CircuitVersion); got != want {
+				t.Errorf("verification key file is %s, want %s", got, want)
+			}
+		})
+	}
+}
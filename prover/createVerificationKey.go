@@ -3,78 +3,81 @@ package prover
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"io"
 	"os"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/consensys/gnark/backend/plonk"
 )
 
-func CreateVkPk() {
-	verificationKeyFile := "verificationKey.json"
-	provingKeyFile := "provingKey.txt"
+// CreateVkPk runs the setup of the batch circuit for common.ProverBackend when its proving or
+// verification key is missing. Both keys are always written together: a key from one setup
+// never verifies proofs made with the other key of another setup.
+func CreateVkPk() error {
+	verificationKeyFile := VerificationKeyFile(common.ProverBackend, common.ProverCurve)
+	provingKeyFile := ProvingKeyFile(common.ProverBackend, common.ProverCurve)
 
-	if _, err := os.Stat(provingKeyFile); os.IsNotExist(err) {
-		if _, err := os.Stat(verificationKeyFile); os.IsNotExist(err) {
-			provingKey, verificationKey, err2 := GenerateVerificationKey()
-			if err2 != nil {
-				fmt.Println("Error generating verification key:", err2)
-			}
-			vkJSON, _ := json.Marshal(verificationKey)
-			vkErr := os.WriteFile(verificationKeyFile, vkJSON, 0644)
-			if vkErr != nil {
-				fmt.Println("Error writing verification key to file:", vkErr)
-			}
-			file, err := os.Create(provingKeyFile)
-			if err != nil {
-				fmt.Println("Error creating file:", err)
-				return
-			}
-			defer func(file *os.File) {
-				err := file.Close()
-				if err != nil {
-					fmt.Println("Error closing file:", err)
-				}
-			}(file)
-			_, err = provingKey.WriteTo(file)
-			if err != nil {
-				fmt.Println("Error writing proving key to buffer:", err)
-			}
-		} else {
-			return
-		}
+	_, pkErr := os.Stat(provingKeyFile)
+	_, vkErr := os.Stat(verificationKeyFile)
+	if pkErr == nil && vkErr == nil {
+		logs.Log.Info("Proving and verification keys already exist. No action needed.")
+		return nil
 	}
-	if _, err := os.Stat(verificationKeyFile); os.IsNotExist(err) {
-		_, verificationKey, error := GenerateVerificationKey()
-		if error != nil {
-			fmt.Println("Error generating verification key:", error)
-		}
-		vkJSON, _ := json.Marshal(verificationKey)
-		vkErr := os.WriteFile(verificationKeyFile, vkJSON, 0644)
-		if vkErr != nil {
-			fmt.Println("Error writing verification key to file:", vkErr)
-		}
-	} else {
-		logs.Log.Info("Verification key already exists. No action needed.")
+	if pkErr == nil || vkErr == nil {
+		logs.Log.Warn(fmt.Sprintf("Only one of %s and %s exists, generating both again", provingKeyFile, verificationKeyFile))
 	}
-	if _, err := os.Stat(provingKeyFile); os.IsNotExist(err) {
-		provingKey, _, err2 := GenerateVerificationKey()
-		if err2 != nil {
-			fmt.Println("Error generating verification key:", err2)
-		}
-		file, err := os.Create(provingKeyFile)
-		if err != nil {
-			fmt.Println("Error creating file:", err)
-			return
-		}
-		defer func(file *os.File) {
-			err := file.Close()
-			if err != nil {
-				fmt.Println("Error closing file:", err)
-			}
-		}(file)
-		_, err = provingKey.WriteTo(file)
-		if err != nil {
-			fmt.Println("Error writing proving key to buffer:", err)
-		}
+
+	var provingKey io.WriterTo
+	var verificationKey any
+	var err error
+	if common.ProverBackend == BackendPlonk {
+		provingKey, verificationKey, err = setupPlonk()
 	} else {
-		logs.Log.Info("Proving key already exists. No action needed.")
+		provingKey, verificationKey, err = GenerateVerificationKey()
+	}
+	if err != nil {
+		return fmt.Errorf("error generating keys: %w", err)
+	}
+
+	return writeKeyPair(provingKeyFile, provingKey, verificationKeyFile, verificationKey)
+}
+
+// setupPlonk runs the PLONK setup from the universal KZG SRS in common.KzgSrsFile.
+func setupPlonk() (plonk.ProvingKey, plonk.VerifyingKey, error) {
+	srs, err := ReadKzgSrsFromFile(common.KzgSrsFile, common.ProverCurve)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading KZG SRS from %s: %w", common.KzgSrsFile, err)
+	}
+
+	ccs, err := compileCircuit(BackendPlonk, common.ProverCurve)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error compiling circuit: %w", err)
+	}
+
+	return plonk.Setup(ccs, srs)
+}
+
+// writeKeyPair writes the proving key to provingKeyFile and the JSON of the verification key
+// to verificationKeyFile. The proving key is written last, so a setup interrupted halfway
+// leaves it missing and is run again on the next start.
+func writeKeyPair(provingKeyFile string, provingKey io.WriterTo, verificationKeyFile string, verificationKey any) error {
+	vkJSON, err := json.Marshal(verificationKey)
+	if err != nil {
+		return fmt.Errorf("error marshalling verification key: %w", err)
+	}
+	if err := os.WriteFile(verificationKeyFile, vkJSON, 0644); err != nil {
+		return fmt.Errorf("error writing verification key to file: %w", err)
+	}
+
+	file, err := os.Create(provingKeyFile)
+	if err != nil {
+		return fmt.Errorf("error creating proving key file: %w", err)
+	}
+	if _, err := provingKey.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(provingKeyFile)
+		return fmt.Errorf("error writing proving key to file: %w", err)
 	}
+	return file.Close()
 }
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package prover
+
+import "os"
+
+// mmapFile falls back to reading the whole file on platforms without mmap.
+func mmapFile(filename string) ([]byte, func(), error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() {}, nil
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package prover
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps filename read-only into memory. The returned function unmaps it.
+func mmapFile(filename string) ([]byte, func(), error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() { _ = syscall.Munmap(data) }, nil
+}
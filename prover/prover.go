@@ -0,0 +1,306 @@
+package prover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	"github.com/airchains-network/evm-sequencer-node/types"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonk_bls12381 "github.com/consensys/gnark/backend/plonk/bls12-381"
+	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// Prover generates the proof for a batch and persists it in the proof store.
+type Prover interface {
+	// Prove proves inputData as batch batchNum, starting from the state root
+	// previousStateHash of the batch before it.
+	Prove(ctx context.Context, inputData types.BatchStruct, batchNum int, previousStateHash string) (*ProofResult, error)
+}
+
+// ProofResult is the output of proving a batch. The witness vector and proof are kept JSON
+// encoded, exactly as they are stored and sent to the settlement layer; the public witness
+// is kept in gnark's binary encoding so the proof can be verified locally.
+type ProofResult struct {
+	Backend           string          `json:"backend"`
+	Curve             string          `json:"curve"`
+	WitnessVector     json.RawMessage `json:"witness_vector"`
+	PublicWitness     []byte          `json:"public_witness"`
+	PreviousStateHash string          `json:"previous_state_hash"`
+	CurrentStateHash  string          `json:"current_state_hash"`
+	Proof             json.RawMessage `json:"proof"`
+}
+
+// LocalProver holds the compiled constraint system and the proving key in memory so that
+// they are computed and loaded once and reused for every batch.
+type LocalProver struct {
+	backend string
+	curve   ecc.ID
+	ccs     constraint.ConstraintSystem
+	pk      provingKey
+}
+
+// NewLocalProver compiles the batch circuit for backend over curve, loads the proving key from
+// provingKeyFile and checks that the key was generated for the compiled constraint system.
+// When useMmap is true the key file is memory-mapped instead of being read through a
+// buffered file.
+func NewLocalProver(backend string, curve string, provingKeyFile string, useMmap bool) (*LocalProver, error) {
+	id, err := curveID(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	ccs, err := compileCircuit(backend, curve)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling circuit: %w", err)
+	}
+
+	if _, err := os.Stat(provingKeyFile); os.IsNotExist(err) {
+		return nil, fmt.Errorf("proving key %s does not exist", provingKeyFile)
+	}
+
+	pk, err := readProvingKey(backend, id, provingKeyFile, useMmap)
+	if err != nil {
+		return nil, fmt.Errorf("error reading proving key: %w", err)
+	}
+
+	if err := validateProvingKey(ccs, pk); err != nil {
+		return nil, err
+	}
+
+	return &LocalProver{backend: backend, curve: id, ccs: ccs, pk: pk}, nil
+}
+
+// Prove proves the batch in-process and stores the proof and public witness for batchNum.
+func (p *LocalProver) Prove(ctx context.Context, inputData types.BatchStruct, batchNum int, previousStateHash string) (*ProofResult, error) {
+	result, err := p.generate(ctx, inputData, batchNum, previousStateHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeProofResult(batchNum, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// storeProofResult saves the witness vector, public witness, state hash, proof and the
+// backend and curve that produced the proof for batchNum. The proof is saved last, so a
+// stored proof implies the rest of the result was saved with it.
+func storeProofResult(batchNum int, result *ProofResult) error {
+	publicWitnessDb := air.GetPublicWitnessDbInstance()
+	publicWitnessDbKey := fmt.Sprintf("public_witness_%d", batchNum)
+	err := publicWitnessDb.Put([]byte(publicWitnessDbKey), result.PublicWitness, nil)
+	if err != nil {
+		return fmt.Errorf("error saving public witness: %w", err)
+	}
+
+	witnessVectorDbKey := fmt.Sprintf("witness_vector_%d", batchNum)
+	err = publicWitnessDb.Put([]byte(witnessVectorDbKey), result.WitnessVector, nil)
+	if err != nil {
+		return fmt.Errorf("error saving witness vector: %w", err)
+	}
+
+	proofDb := air.GetProofDbInstance()
+	stateHashDbKey := fmt.Sprintf("proof_state_hash_%d", batchNum)
+	err = proofDb.Put([]byte(stateHashDbKey), []byte(result.CurrentStateHash), nil)
+	if err != nil {
+		return fmt.Errorf("error saving state hash: %w", err)
+	}
+
+	previousStateHashDbKey := fmt.Sprintf("proof_previous_state_hash_%d", batchNum)
+	err = proofDb.Put([]byte(previousStateHashDbKey), []byte(result.PreviousStateHash), nil)
+	if err != nil {
+		return fmt.Errorf("error saving previous state hash: %w", err)
+	}
+
+	proofBackendDbKey := fmt.Sprintf("proof_backend_%d", batchNum)
+	err = proofDb.Put([]byte(proofBackendDbKey), []byte(result.Backend), nil)
+	if err != nil {
+		return fmt.Errorf("error saving proof backend: %w", err)
+	}
+
+	proofCurveDbKey := fmt.Sprintf("proof_curve_%d", batchNum)
+	err = proofDb.Put([]byte(proofCurveDbKey), []byte(result.Curve), nil)
+	if err != nil {
+		return fmt.Errorf("error saving proof curve: %w", err)
+	}
+
+	proofDbKey := fmt.Sprintf("proof_%d", batchNum)
+	err = proofDb.Put([]byte(proofDbKey), result.Proof, nil)
+	if err != nil {
+		return fmt.Errorf("error saving proof: %w", err)
+	}
+
+	return nil
+}
+
+// StoredProofResult returns the result saved when batchNum was proved, so a batch whose
+// proof survived a restart is not proved again.
+func StoredProofResult(batchNum int) (*ProofResult, error) {
+	proofDb := air.GetProofDbInstance()
+	proof, err := proofDb.Get([]byte(fmt.Sprintf("proof_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting proof from db: %w", err)
+	}
+	stateHash, err := proofDb.Get([]byte(fmt.Sprintf("proof_state_hash_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting state hash from db: %w", err)
+	}
+	// proofs stored before the previous state hash was recorded are proved again
+	previousStateHash, err := proofDb.Get([]byte(fmt.Sprintf("proof_previous_state_hash_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting previous state hash from db: %w", err)
+	}
+	publicWitness, err := air.GetPublicWitnessDbInstance().Get([]byte(fmt.Sprintf("public_witness_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting public witness from db: %w", err)
+	}
+	witnessVector, err := WitnessVector(batchNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProofResult{
+		Backend:           ProofBackend(batchNum),
+		Curve:             ProofCurve(batchNum),
+		WitnessVector:     witnessVector,
+		PublicWitness:     publicWitness,
+		PreviousStateHash: string(previousStateHash),
+		CurrentStateHash:  string(stateHash),
+		Proof:             proof,
+	}, nil
+}
+
+// DeleteProofResult removes the stored proof of batchNum, so the batch is proved again.
+func DeleteProofResult(batchNum int) error {
+	return air.GetProofDbInstance().Delete([]byte(fmt.Sprintf("proof_%d", batchNum)), nil)
+}
+
+// ProofBackend returns the backend that produced the proof of batchNum. Batches proved
+// before the backend was recorded are Groth16.
+func ProofBackend(batchNum int) string {
+	backend, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_backend_%d", batchNum)), nil)
+	if err != nil || len(backend) == 0 {
+		return BackendGroth16
+	}
+	return string(backend)
+}
+
+// ProofCurve returns the curve of the proof of batchNum. Batches proved before the curve
+// was recorded are BLS12-381.
+func ProofCurve(batchNum int) string {
+	curve, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_curve_%d", batchNum)), nil)
+	if err != nil || len(curve) == 0 {
+		return CurveBLS12_381
+	}
+	return string(curve)
+}
+
+// WitnessVector returns the JSON witness vector of batchNum, as sent to the settlement
+// layer with the pod. Batches proved before the vector was stored have it rebuilt from the
+// stored public witness.
+func WitnessVector(batchNum int) (json.RawMessage, error) {
+	witnessVector, err := air.GetPublicWitnessDbInstance().Get([]byte(fmt.Sprintf("witness_vector_%d", batchNum)), nil)
+	if err == nil {
+		return witnessVector, nil
+	}
+
+	id, err := curveID(ProofCurve(batchNum))
+	if err != nil {
+		return nil, err
+	}
+
+	publicWitnessBytes, err := air.GetPublicWitnessDbInstance().Get([]byte(fmt.Sprintf("public_witness_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting public witness from db: %w", err)
+	}
+
+	publicWitness, err := witness.New(id.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	if err := publicWitness.UnmarshalBinary(publicWitnessBytes); err != nil {
+		return nil, fmt.Errorf("error decoding public witness: %w", err)
+	}
+
+	return json.Marshal(publicWitness.Vector())
+}
+
+// readProvingKey reads the proving key of backend over curve id from filename. A memory-mapped key is
+// decoded without the subgroup checks, so useMmap trusts that the file was written by CreateVkPk and
+// not altered since.
+func readProvingKey(backend string, id ecc.ID, filename string, useMmap bool) (provingKey, error) {
+	pk, err := newProvingKey(backend, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if useMmap {
+		data, unmap, err := mmapFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer unmap()
+
+		_, err = pk.UnsafeReadFrom(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return pk, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = pk.ReadFrom(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// validateProvingKey makes sure the proving key belongs to the compiled circuit, so a
+// stale key left over from a different BatchSize or curve is rejected at startup instead
+// of failing inside the prover.
+func validateProvingKey(ccs constraint.ConstraintSystem, pk provingKey) error {
+	groth16Size := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints()))
+	plonkSize := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints() + ccs.GetNbPublicVariables()))
+
+	var curve ecc.ID
+	var size, expected uint64
+	switch _pk := pk.(type) {
+	case *groth16_bls12381.ProvingKey:
+		curve, size, expected = ecc.BLS12_381, _pk.Domain.Cardinality, groth16Size
+	case *groth16_bn254.ProvingKey:
+		curve, size, expected = ecc.BN254, _pk.Domain.Cardinality, groth16Size
+	case *plonk_bls12381.ProvingKey:
+		curve, size, expected = ecc.BLS12_381, _pk.Domain[0].Cardinality, plonkSize
+	case *plonk_bn254.ProvingKey:
+		curve, size, expected = ecc.BN254, _pk.Domain[0].Cardinality, plonkSize
+	default:
+		return fmt.Errorf("unsupported proving key type %T", pk)
+	}
+
+	if curve.ScalarField().Cmp(ccs.Field()) != 0 {
+		return fmt.Errorf("proving key curve %s does not match the circuit scalar field", curve)
+	}
+	if size != expected {
+		return fmt.Errorf("proving key domain size %d does not match circuit domain size %d", size, expected)
+	}
+
+	return nil
+}
@@ -1,43 +1,54 @@
 package prover
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"math/rand"
-	"os"
 	"time"
 
-	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
 	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
 	"github.com/airchains-network/evm-sequencer-node/types"
 
-	"github.com/consensys/gnark-crypto/ecc"
-	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
-	"github.com/consensys/gnark-crypto/hash"
 	cryptoEddsa "github.com/consensys/gnark-crypto/signature/eddsa"
 	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/std/algebra/native/twistededwards"
 	"github.com/consensys/gnark/std/hash/mimc"
 	"github.com/consensys/gnark/std/signature/eddsa"
 )
 
+// MyCircuit proves the transactions of a batch. BatchNumber, PreviousStateRoot and StateRoot
+// state which batch it is and the state roots it goes from and to; the roots are computed
+// outside the circuit from the transactions and split into their high and low 128 bits, as
+// stateRootLimbs does. They come first so they are the first public inputs of the proof,
+// where AggregationCircuit reads them.
 type MyCircuit struct {
-	To              [common.BatchSize]frontend.Variable `gnark:",public"`
-	From            [common.BatchSize]frontend.Variable `gnark:",public"`
-	Amount          [common.BatchSize]frontend.Variable `gnark:",public"`
-	TransactionHash [common.BatchSize]frontend.Variable `gnark:",public"`
-	FromBalances    [common.BatchSize]frontend.Variable `gnark:",public"`
-	ToBalances      [common.BatchSize]frontend.Variable `gnark:",public"`
-	Messages        [common.BatchSize]frontend.Variable `gnark:",public"`
-	PublicKeys      [common.BatchSize]eddsa.PublicKey   `gnark:",public"`
-	Signatures      [common.BatchSize]eddsa.Signature   `gnark:",public"`
+	BatchNumber       frontend.Variable                   `gnark:",public"`
+	PreviousStateRoot [2]frontend.Variable                `gnark:",public"`
+	StateRoot         [2]frontend.Variable                `gnark:",public"`
+	To                [common.BatchSize]frontend.Variable `gnark:",public"`
+	From              [common.BatchSize]frontend.Variable `gnark:",public"`
+	Amount            [common.BatchSize]frontend.Variable `gnark:",public"`
+	TransactionHash   [common.BatchSize]frontend.Variable `gnark:",public"`
+	FromBalances      [common.BatchSize]frontend.Variable `gnark:",public"`
+	ToBalances        [common.BatchSize]frontend.Variable `gnark:",public"`
+	Messages          [common.BatchSize]frontend.Variable `gnark:",public"`
+	PublicKeys        [common.BatchSize]eddsa.PublicKey   `gnark:",public"`
+	Signatures        [common.BatchSize]eddsa.Signature   `gnark:",public"`
 }
 
+// statementInputs is the number of public inputs of MyCircuit set by the batch, from
+// BatchNumber to Messages. The public keys and signatures after them are drawn by the prover.
+const statementInputs = 5 + 7*common.BatchSize
+
 type TransactionSecond struct {
 	To              string
 	From            string
@@ -78,13 +89,43 @@ func GetMerkleRootSecond(transactions []TransactionSecond) string {
 	return merkleTree[0]
 }
 
+// BatchStateHash returns the state root of a batch, the merkle root of its transactions.
+// Padding added for the circuit is not part of it.
+func BatchStateHash(inputData types.BatchStruct) string {
+	var transactions []TransactionSecond
+	for i := range inputData.TransactionHash {
+		transaction := TransactionSecond{
+			To:              inputData.To[i],
+			From:            inputData.From[i],
+			Amount:          inputData.Amounts[i],
+			FromBalances:    inputData.SenderBalances[i],
+			ToBalances:      inputData.ReceiverBalances[i],
+			TransactionHash: inputData.TransactionHash[i],
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return GetMerkleRootSecond(transactions)
+}
+
 func (circuit *MyCircuit) Define(api frontend.API) error {
+	curveID, err := curveFromField(api.Compiler().Field())
+	if err != nil {
+		return err
+	}
+
+	// range checks bind the statement to the proof and keep the limbs canonical
+	api.ToBinary(circuit.BatchNumber, 64)
+	for i := 0; i < 2; i++ {
+		api.ToBinary(circuit.PreviousStateRoot[i], 128)
+		api.ToBinary(circuit.StateRoot[i], 128)
+	}
+
 	for i := 0; i < common.BatchSize; i++ {
 
-		curve, err := twistededwards.NewEdCurve(api, tedwards.ID(ecc.BLS12_381))
+		curve, err := twistededwards.NewEdCurve(api, twistedEdwardsID(curveID))
 		if err != nil {
-			fmt.Println("Error creating a curve")
-			return err
+			return fmt.Errorf("error creating a curve: %w", err)
 		}
 		newMiMC, err := mimc.NewMiMC(api)
 		if err != nil {
@@ -92,8 +133,7 @@ func (circuit *MyCircuit) Define(api frontend.API) error {
 		}
 		err = eddsa.Verify(curve, circuit.Signatures[i], circuit.Messages[i], circuit.PublicKeys[i], &newMiMC)
 		if err != nil {
-			fmt.Println("Error verifying signature")
-			return err
+			return fmt.Errorf("error verifying signature: %w", err)
 		}
 		api.AssertIsLessOrEqual(circuit.Amount[i], circuit.FromBalances[i])
 
@@ -110,57 +150,79 @@ func (circuit *MyCircuit) Define(api frontend.API) error {
 	return nil
 }
 
-func ComputeCCS() constraint.ConstraintSystem {
-	var circuit MyCircuit
-	ccs, _ := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &circuit)
-
-	return ccs
+func ComputeCCS() (constraint.ConstraintSystem, error) {
+	return compileCircuit(BackendGroth16, common.ProverCurve)
 }
 
 func GenerateVerificationKey() (groth16.ProvingKey, groth16.VerifyingKey, error) {
-	ccs := ComputeCCS()
+	ccs, err := ComputeCCS()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error compiling circuit: %w", err)
+	}
 	pk, vk, error := groth16.Setup(ccs)
 	return pk, vk, error
 }
 
-func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []byte, error) {
-
-	ccs := ComputeCCS()
+// generate builds the witness for a batch and proves it with the cached constraint
+// system and proving key. Persisting the result is left to the caller.
+func (p *LocalProver) generate(ctx context.Context, inputData types.BatchStruct, batchNum int, previousStateHash string) (*ProofResult, error) {
+	_, span := tracing.Start(ctx, "build-witness")
+	witness, currentStatusHash, err := p.buildWitness(inputData, batchNum, previousStateHash)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
 
-	var transactions []TransactionSecond
-	for i := 0; i < common.BatchSize; i++ {
-		transaction := TransactionSecond{
-			To:              inputData.To[i],
-			From:            inputData.From[i],
-			Amount:          inputData.Amounts[i],
-			FromBalances:    inputData.SenderBalances[i],
-			ToBalances:      inputData.ReceiverBalances[i],
-			TransactionHash: inputData.TransactionHash[i],
-		}
-		transactions = append(transactions, transaction)
+	witnessVector, err := json.Marshal(witness.Vector())
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling witness vector: %w", err)
 	}
 
-	currentStatusHash := GetMerkleRootSecond(transactions)
-	
-	if _, err := os.Stat("provingKey.txt"); os.IsNotExist(err) {
-		fmt.Println("Proving key does not exist. Please run the command 'sequencer-sdk create-vk-pk' to generate the proving key")
-		return nil, "", nil, err
+	publicWitness, _ := witness.Public()
+	publicWitnessValue, err := publicWitness.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling public witness: %w", err)
 	}
 
-	pk, err := ReadProvingKeyFromFile("provingKey.txt")
+	_, span = tracing.Start(ctx, p.backend+".Prove")
+	var proof any
+	switch p.backend {
+	case BackendPlonk:
+		proof, err = plonk.Prove(p.ccs, p.pk.(plonk.ProvingKey), witness)
+	default:
+		proof, err = groth16.Prove(p.ccs, p.pk.(groth16.ProvingKey), witness)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("error generating proof: %w", err)
+	}
 
+	proofValue, err := json.Marshal(proof)
 	if err != nil {
-		fmt.Println("Error reading proving key:", err)
-		return nil, "", nil, err
+		return nil, fmt.Errorf("error marshalling proof: %w", err)
 	}
 
+	return &ProofResult{
+		Backend:           p.backend,
+		Curve:             p.curve.String(),
+		WitnessVector:     witnessVector,
+		PublicWitness:     publicWitnessValue,
+		PreviousStateHash: previousStateHash,
+		CurrentStateHash:  currentStatusHash,
+		Proof:             proofValue,
+	}, nil
+}
+
+// buildWitness pads the batch to BatchSize, signs its messages and returns the witness of
+// the circuit for batchNum going from previousStateHash, with the state hash of the batch.
+func (p *LocalProver) buildWitness(inputData types.BatchStruct, batchNum int, previousStateHash string) (witness.Witness, string, error) {
 	seed := time.Now().Unix()
 	randomness := rand.New(rand.NewSource(seed))
-	hFunc := hash.MIMC_BLS12_381.New()
-	snarkField, err := twistededwards.GetSnarkField(tedwards.BLS12_381)
+	edwardsCurve := twistedEdwardsID(p.curve)
+	hFunc := mimcHash(p.curve).New()
+	snarkField, err := twistededwards.GetSnarkField(edwardsCurve)
 	if err != nil {
-		fmt.Println("Error getting snark field")
-		return nil, "", nil, err
+		return nil, "", fmt.Errorf("error getting snark field: %w", err)
 	}
 	var inputValueLength int
 
@@ -184,8 +246,21 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		fromLength == accountNoncesLength {
 		inputValueLength = fromLength
 	} else {
-		fmt.Println("Error: Input data is not correct")
-		return nil, "", nil, fmt.Errorf("input data is not correct")
+		return nil, "", fmt.Errorf("input data is not correct")
+	}
+
+	if inputValueLength == 0 || inputValueLength > common.BatchSize {
+		return nil, "", fmt.Errorf("batch has %d transactions, the circuit takes 1 to %d", inputValueLength, common.BatchSize)
+	}
+
+	currentStatusHash := BatchStateHash(inputData)
+	previousStateRoot, err := stateRootLimbs(previousStateHash)
+	if err != nil {
+		return nil, "", err
+	}
+	stateRoot, err := stateRootLimbs(currentStatusHash)
+	if err != nil {
+		return nil, "", err
 	}
 
 	if inputValueLength < common.BatchSize {
@@ -204,25 +279,32 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 	}
 
 	inputs := MyCircuit{
-		To:              [common.BatchSize]frontend.Variable{},
-		From:            [common.BatchSize]frontend.Variable{},
-		Amount:          [common.BatchSize]frontend.Variable{},
-		TransactionHash: [common.BatchSize]frontend.Variable{},
-		FromBalances:    [common.BatchSize]frontend.Variable{},
-		ToBalances:      [common.BatchSize]frontend.Variable{},
-		Signatures:      [common.BatchSize]eddsa.Signature{},
-		PublicKeys:      [common.BatchSize]eddsa.PublicKey{},
-		Messages:        [common.BatchSize]frontend.Variable{},
+		BatchNumber:       batchNum,
+		PreviousStateRoot: previousStateRoot,
+		StateRoot:         stateRoot,
+		To:                [common.BatchSize]frontend.Variable{},
+		From:              [common.BatchSize]frontend.Variable{},
+		Amount:            [common.BatchSize]frontend.Variable{},
+		TransactionHash:   [common.BatchSize]frontend.Variable{},
+		FromBalances:      [common.BatchSize]frontend.Variable{},
+		ToBalances:        [common.BatchSize]frontend.Variable{},
+		Signatures:        [common.BatchSize]eddsa.Signature{},
+		PublicKeys:        [common.BatchSize]eddsa.PublicKey{},
+		Messages:          [common.BatchSize]frontend.Variable{},
 	}
 
 	for i := 0; i < common.BatchSize; i++ {
 		// var amount string
-		if inputData.Amounts[i] > inputData.SenderBalances[i] {
-			fmt.Println("Amount value give below")
-			fmt.Println(inputData.Amounts[i])
-			fmt.Println("From balance value given below")
-			fmt.Println(inputData.SenderBalances[i])
-			os.Exit(90)
+		amount, ok := new(big.Int).SetString(inputData.Amounts[i], 10)
+		if !ok {
+			return nil, "", fmt.Errorf("amount %q of transaction %d is not a decimal number", inputData.Amounts[i], i)
+		}
+		senderBalance, ok := new(big.Int).SetString(inputData.SenderBalances[i], 10)
+		if !ok {
+			return nil, "", fmt.Errorf("sender balance %q of transaction %d is not a decimal number", inputData.SenderBalances[i], i)
+		}
+		if amount.Cmp(senderBalance) > 0 {
+			return nil, "", fmt.Errorf("amount %s of transaction %d is above the sender balance %s", inputData.Amounts[i], i, inputData.SenderBalances[i])
 		}
 		inputs.To[i] = frontend.Variable(inputData.To[i])
 		inputs.From[i] = frontend.Variable(inputData.From[i])
@@ -234,86 +316,25 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		msg := make([]byte, len(snarkField.Bytes()))
 
 		inputs.Messages[i] = msg
-		privateKey, err := cryptoEddsa.New(tedwards.ID(ecc.BLS12_381), randomness)
+		privateKey, err := cryptoEddsa.New(edwardsCurve, randomness)
 		if err != nil {
-			fmt.Println("Not able to generate private keys")
+			return nil, "", fmt.Errorf("error generating private key: %w", err)
 		}
 		publicKey := privateKey.Public()
 		signature, err := privateKey.Sign(msg, hFunc)
 		if err != nil {
-			fmt.Println("Error signing the message")
-			return nil, "", nil, err
+			return nil, "", fmt.Errorf("error signing the message: %w", err)
 		}
 		_publicKey := publicKey.Bytes()
 
-		inputs.PublicKeys[i].Assign(tedwards.BLS12_381, _publicKey[:32])
-		inputs.Signatures[i].Assign(tedwards.BLS12_381, signature)
-	}
-
-	
-	witness, err := frontend.NewWitness(&inputs, ecc.BLS12_381.ScalarField())
-	if err != nil {
-		// fmt.Println(inputs.From)
-		// fmt.Println(inputs.To)
-		fmt.Println(inputs.Amount)
-		fmt.Println(inputs.FromBalances)
-		fmt.Println(inputs.ToBalances)
-
-		fmt.Printf("Error creating a witness: %v\n", err)
-		return nil, "", nil, err
-	}
-	// fmt.Println("Witness created")
-	// os.Exit(0)
-
-	witnessVector := witness.Vector()
-
-	publicWitness, _ := witness.Public()
-	publicWitnessDb := air.GetPublicWitnessDbInstance()
-	publicWitnessDbKey := fmt.Sprintf("public_witness_%d", batchNum)
-	publicWitnessDbValue, err := json.Marshal(publicWitness)
-	if err != nil {
-		fmt.Println("Error marshalling public witness:", err)
-		return nil, "", nil, err
-	}
-	err = publicWitnessDb.Put([]byte(publicWitnessDbKey), publicWitnessDbValue, nil)
-	if err != nil {
-		fmt.Println("Error saving public witness:", err)
-		return nil, "", nil, err
-	}
-	proof, err := groth16.Prove(ccs, pk, witness)
-	if err != nil {
-		fmt.Printf("Error generating proof: %v\n", err)
-		return nil, "", nil, err
-	}
-
-	proofDb := air.GetProofDbInstance()
-	proofDbKey := fmt.Sprintf("proof_%d", batchNum)
-	proofDbValue, err := json.Marshal(proof)
-	if err != nil {
-		fmt.Println("Error marshalling proof:", err)
-		return nil, "", nil, err
-	}
-	err = proofDb.Put([]byte(proofDbKey), proofDbValue, nil)
-	if err != nil {
-		fmt.Println("Error saving proof:", err)
-		return nil, "", nil, err
-	}
-
-	return witnessVector, currentStatusHash, proofDbValue, nil
-}
-
-func ReadProvingKeyFromFile(filename string) (groth16.ProvingKey, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+		inputs.PublicKeys[i].Assign(edwardsCurve, _publicKey[:32])
+		inputs.Signatures[i].Assign(edwardsCurve, signature)
 	}
-	defer file.Close()
 
-	pk := groth16.NewProvingKey(ecc.BLS12_381)
-	_, err = pk.ReadFrom(file)
+	w, err := frontend.NewWitness(&inputs, p.curve.ScalarField())
 	if err != nil {
-		return nil, err
+		return nil, "", fmt.Errorf("error creating a witness: %w", err)
 	}
 
-	return pk, nil
+	return w, currentStatusHash, nil
 }
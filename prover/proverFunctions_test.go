@@ -0,0 +1,88 @@
+package prover
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+func testBatch() types.BatchStruct {
+	return types.BatchStruct{
+		From:              []string{"0x0a"},
+		To:                []string{"0x0b"},
+		Amounts:           []string{"10"},
+		TransactionHash:   []string{"0x01"},
+		SenderBalances:    []string{"100"},
+		ReceiverBalances:  []string{"5"},
+		Messages:          []string{"m"},
+		TransactionNonces: []string{"0"},
+		AccountNonces:     []string{"0"},
+	}
+}
+
+// TestBuildWitnessStatement checks that the batch number and state root limbs are the first
+// public inputs, where AggregationCircuit expects them.
+func TestBuildWitnessStatement(t *testing.T) {
+	p := &LocalProver{backend: BackendGroth16, curve: ecc.BN254}
+	batch := testBatch()
+	previousHash := "ff" + strings.Repeat("00", 15) + strings.Repeat("11", 16)
+
+	w, stateHash, err := p.buildWitness(batch, 7, previousHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stateHash != BatchStateHash(batch) {
+		t.Fatalf("got state hash %s, want %s", stateHash, BatchStateHash(batch))
+	}
+
+	public, err := w.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vector := public.Vector().(fr.Vector)
+
+	previousRoot, _ := stateRootLimbs(previousHash)
+	root, _ := stateRootLimbs(stateHash)
+	want := []*big.Int{
+		big.NewInt(7),
+		previousRoot[0].(*big.Int), previousRoot[1].(*big.Int),
+		root[0].(*big.Int), root[1].(*big.Int),
+	}
+	for i, v := range want {
+		var got big.Int
+		vector[i].BigInt(&got)
+		if got.Cmp(v) != 0 {
+			t.Errorf("public input %d is %s, want %s", i, got.String(), v.String())
+		}
+	}
+}
+
+func TestBuildWitnessRejects(t *testing.T) {
+	p := &LocalProver{backend: BackendGroth16, curve: ecc.BN254}
+
+	overdrawn := testBatch()
+	overdrawn.Amounts[0] = "101"
+
+	tests := []struct {
+		name         string
+		batch        types.BatchStruct
+		previousHash string
+		wantErr      string
+	}{
+		{"invalid previous root", testBatch(), "not-hex", "invalid state root"},
+		{"amount above balance", overdrawn, "0", "above the sender balance"},
+		{"empty batch", types.BatchStruct{}, "0", "the circuit takes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := p.buildWitness(tt.batch, 1, tt.previousHash)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
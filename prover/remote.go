@@ -0,0 +1,340 @@
+package prover
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/common/logs"
+	"github.com/airchains-network/evm-sequencer-node/tracing"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"github.com/consensys/gnark/backend/witness"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	proverServiceName = "prover.ProverWorker"
+	proveMethod       = "/" + proverServiceName + "/Prove"
+
+	JobStatusDispatched = "dispatched"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+)
+
+// ProveJobRequest is the witness job sent to a prover worker.
+type ProveJobRequest struct {
+	JobID             string            `json:"job_id"`
+	BatchNumber       int               `json:"batch_number"`
+	PreviousStateHash string            `json:"previous_state_hash"`
+	Batch             types.BatchStruct `json:"batch"`
+}
+
+// ProveJobResponse is the proof returned by a prover worker.
+type ProveJobResponse struct {
+	JobID  string      `json:"job_id"`
+	Result ProofResult `json:"result"`
+}
+
+// jsonCodec lets the prover service run over gRPC with plain JSON messages, so the
+// request and response types above need no generated protobuf code.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// WorkerServer serves proving jobs from a LocalProver. Jobs are proved one at a time
+// since a single groth16.Prove already uses every core of the worker.
+type WorkerServer struct {
+	prover *LocalProver
+	mu     sync.Mutex
+}
+
+func NewWorkerServer(prover *LocalProver) *WorkerServer {
+	return &WorkerServer{prover: prover}
+}
+
+// Prove generates the proof for a job without touching any store; persisting the result
+// is done by the sequencer that dispatched the job.
+func (w *WorkerServer) Prove(ctx context.Context, req *ProveJobRequest) (*ProveJobResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// the job continues the trace of the batch on the sequencer
+	headers := map[string]string{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			headers[key] = values[0]
+		}
+	}
+	ctx, span := tracing.Start(tracing.Extract(ctx, headers), "prove-job", attribute.String("job.id", req.JobID))
+
+	logs.Log.With("batch", req.BatchNumber, "job", req.JobID).Info(fmt.Sprintf("Proving job %s for batch %d", req.JobID, req.BatchNumber))
+	result, err := w.prover.generate(ctx, req.Batch, req.BatchNumber, req.PreviousStateHash)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProveJobResponse{JobID: req.JobID, Result: *result}, nil
+}
+
+type proverWorkerServer interface {
+	Prove(ctx context.Context, req *ProveJobRequest) (*ProveJobResponse, error)
+}
+
+func proveHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := new(ProveJobRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(proverWorkerServer).Prove(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: proveMethod}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(proverWorkerServer).Prove(ctx, req.(*ProveJobRequest))
+	}
+	return interceptor(ctx, req, info, handler)
+}
+
+var proverServiceDesc = grpc.ServiceDesc{
+	ServiceName: proverServiceName,
+	HandlerType: (*proverWorkerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Prove", Handler: proveHandler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "prover/remote.go",
+}
+
+// RegisterWorkerServer registers the prover service on a gRPC server.
+func RegisterWorkerServer(s *grpc.Server, w *WorkerServer) {
+	s.RegisterService(&proverServiceDesc, w)
+}
+
+type proverWorker struct {
+	addr string
+	conn *grpc.ClientConn
+}
+
+// RemoteProver dispatches proving jobs to prover worker processes over gRPC, retrying
+// on the next worker when a job fails or times out.
+type RemoteProver struct {
+	workers []proverWorker
+	timeout time.Duration
+	retries int
+	next    atomic.Uint32
+}
+
+// NewRemoteProver connects to the prover workers at addrs, verifying them against the CA in
+// caFile when it is set and authenticating with token when it is set. Each job may take
+// timeout and is attempted retries times.
+func NewRemoteProver(addrs []string, timeout time.Duration, retries int, caFile string, token string) (*RemoteProver, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no prover workers configured")
+	}
+	if retries < 1 {
+		return nil, fmt.Errorf("prover job retries must be at least 1, got %d", retries)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("prover job timeout must be positive, got %s", timeout)
+	}
+
+	opts, err := workerDialOptions(caFile, token)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())))
+
+	r := &RemoteProver{timeout: timeout, retries: retries}
+	for _, addr := range addrs {
+		conn, err := grpc.Dial(addr, opts...)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("error connecting to prover worker %s: %w", addr, err)
+		}
+		r.workers = append(r.workers, proverWorker{addr: addr, conn: conn})
+	}
+
+	return r, nil
+}
+
+// Prove sends the batch to the workers in round-robin order until one returns a proof of
+// the batch or the retries are exhausted, then stores the proof and public witness for
+// batchNum. A result that does not prove the batch that was sent counts as a failed attempt.
+func (r *RemoteProver) Prove(ctx context.Context, inputData types.BatchStruct, batchNum int, previousStateHash string) (*ProofResult, error) {
+	jobID, err := newJobID(batchNum)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ProveJobRequest{JobID: jobID, BatchNumber: batchNum, PreviousStateHash: previousStateHash, Batch: inputData}
+
+	var lastErr error
+	for attempt := 1; attempt <= r.retries; attempt++ {
+		worker := r.workers[int(r.next.Add(1)-1)%len(r.workers)]
+		saveProofJob(jobID, batchNum, worker.addr, attempt, JobStatusDispatched, nil)
+
+		dispatchCtx, span := tracing.Start(ctx, "dispatch", attribute.String("worker", worker.addr), attribute.Int("attempt", attempt))
+		res, err := r.dispatch(dispatchCtx, worker, req)
+		if err == nil {
+			err = checkProofResult(&res.Result, inputData, batchNum, previousStateHash)
+		}
+		tracing.End(span, err)
+		if err == nil {
+			if err := storeProofResult(batchNum, &res.Result); err != nil {
+				return nil, err
+			}
+			saveProofJob(jobID, batchNum, worker.addr, attempt, JobStatusCompleted, nil)
+			return &res.Result, nil
+		}
+
+		lastErr = err
+		saveProofJob(jobID, batchNum, worker.addr, attempt, JobStatusFailed, err)
+		logs.FromContext(ctx).Warn(fmt.Sprintf("Proving job %s failed on worker %s (attempt %d/%d) : %s", jobID, worker.addr, attempt, r.retries, err.Error()))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("proving job %s for batch %d failed: %w", jobID, batchNum, lastErr)
+}
+
+func (r *RemoteProver) dispatch(ctx context.Context, worker proverWorker, req *ProveJobRequest) (*ProveJobResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	for key, value := range tracing.Inject(ctx) {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+
+	res := new(ProveJobResponse)
+	if err := worker.conn.Invoke(ctx, proveMethod, req, res); err != nil {
+		return nil, err
+	}
+	if res.JobID != req.JobID {
+		return nil, fmt.Errorf("worker returned job %s, expected %s", res.JobID, req.JobID)
+	}
+
+	return res, nil
+}
+
+// checkProofResult makes sure a worker proved the batch it was sent: with ProverBackend over
+// ProverCurve, from previousStateHash to the state root of inputData, and with the public
+// witness built locally from the batch. Only the public keys and signatures at the end of
+// the witness are drawn by the prover and may differ.
+func checkProofResult(result *ProofResult, inputData types.BatchStruct, batchNum int, previousStateHash string) error {
+	if result.Backend != common.ProverBackend || result.Curve != common.ProverCurve {
+		return fmt.Errorf("worker proved with %s over %s, expected %s over %s", result.Backend, result.Curve, common.ProverBackend, common.ProverCurve)
+	}
+	if result.PreviousStateHash != previousStateHash {
+		return fmt.Errorf("worker proved from state root %s, expected %s", result.PreviousStateHash, previousStateHash)
+	}
+	if stateHash := BatchStateHash(inputData); result.CurrentStateHash != stateHash {
+		return fmt.Errorf("worker proved state root %s, expected %s", result.CurrentStateHash, stateHash)
+	}
+
+	id, err := curveID(result.Curve)
+	if err != nil {
+		return err
+	}
+	publicWitness, err := witness.New(id.ScalarField())
+	if err != nil {
+		return err
+	}
+	if err := publicWitness.UnmarshalBinary(result.PublicWitness); err != nil {
+		return fmt.Errorf("error decoding public witness from worker: %w", err)
+	}
+	witnessVector, err := json.Marshal(publicWitness.Vector())
+	if err != nil {
+		return fmt.Errorf("error marshalling witness vector: %w", err)
+	}
+	if !bytes.Equal(witnessVector, result.WitnessVector) {
+		return fmt.Errorf("witness vector from worker does not match its public witness")
+	}
+
+	local := &LocalProver{backend: result.Backend, curve: id}
+	expectedWitness, _, err := local.buildWitness(inputData, batchNum, previousStateHash)
+	if err != nil {
+		return err
+	}
+	expectedPublic, err := expectedWitness.Public()
+	if err != nil {
+		return err
+	}
+
+	got := reflect.ValueOf(publicWitness.Vector())
+	expected := reflect.ValueOf(expectedPublic.Vector())
+	if got.Len() != expected.Len() {
+		return fmt.Errorf("public witness from worker has %d inputs, expected %d", got.Len(), expected.Len())
+	}
+	for i := 0; i < statementInputs; i++ {
+		if got.Index(i).Interface() != expected.Index(i).Interface() {
+			return fmt.Errorf("public input %d from worker does not match batch %d", i, batchNum)
+		}
+	}
+	return nil
+}
+
+// Close closes the connections to the prover workers.
+func (r *RemoteProver) Close() {
+	for _, worker := range r.workers {
+		_ = worker.conn.Close()
+	}
+}
+
+func newJobID(batchNum int) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", batchNum, hex.EncodeToString(b)), nil
+}
+
+// saveProofJob records the state of a proving job in the proof db. Failing to record it
+// is logged but does not fail the job.
+func saveProofJob(jobID string, batchNum int, worker string, attempt int, status string, jobErr error) {
+	job := types.ProofJobStruct{
+		JobID:       jobID,
+		BatchNumber: batchNum,
+		Worker:      worker,
+		Attempt:     attempt,
+		Status:      status,
+		UpdatedAt:   time.Now().Unix(),
+	}
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in marshalling proof job : %s", err.Error()))
+		return
+	}
+
+	err = air.GetProofDbInstance().Put([]byte(fmt.Sprintf("proof_job_%s", jobID)), jobBytes, nil)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in saving proof job in proof db : %s", err.Error()))
+	}
+}
@@ -0,0 +1,258 @@
+package prover
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/evm-sequencer-node/common"
+	"github.com/airchains-network/evm-sequencer-node/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeWorker answers proving jobs with a fixed result, echoing the job ID unless jobID is set.
+type fakeWorker struct {
+	jobID string
+}
+
+func (f *fakeWorker) Prove(ctx context.Context, req *ProveJobRequest) (*ProveJobResponse, error) {
+	jobID := req.JobID
+	if f.jobID != "" {
+		jobID = f.jobID
+	}
+	return &ProveJobResponse{JobID: jobID, Result: ProofResult{CurrentStateHash: "state-" + req.Batch.From[0]}}, nil
+}
+
+func startFakeWorker(t *testing.T, worker *fakeWorker, token string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := WorkerServerOptions("", "", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&proverServiceDesc, worker)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	req := &ProveJobRequest{
+		JobID:             "7-0011223344556677",
+		BatchNumber:       7,
+		PreviousStateHash: "ab01",
+		Batch:             types.BatchStruct{From: []string{"0xa"}, To: []string{"0xb"}, Amounts: []string{"10"}},
+	}
+	data, err := jsonCodec{}.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(ProveJobRequest)
+	if err := (jsonCodec{}).Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(req, got) {
+		t.Fatalf("round trip gave %+v, want %+v", got, req)
+	}
+}
+
+func TestNewRemoteProverValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		addrs   []string
+		timeout time.Duration
+		retries int
+		wantErr string
+	}{
+		{"no workers", nil, time.Second, 1, "no prover workers"},
+		{"zero retries", []string{"127.0.0.1:1"}, time.Second, 0, "retries must be at least 1"},
+		{"negative retries", []string{"127.0.0.1:1"}, time.Second, -2, "retries must be at least 1"},
+		{"zero timeout", []string{"127.0.0.1:1"}, 0, 1, "timeout must be positive"},
+		{"valid", []string{"127.0.0.1:1"}, time.Second, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRemoteProver(tt.addrs, tt.timeout, tt.retries, "", "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				r.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	req := &ProveJobRequest{JobID: "3-aa", BatchNumber: 3, Batch: types.BatchStruct{From: []string{"0xa"}}}
+
+	tests := []struct {
+		name        string
+		worker      *fakeWorker
+		workerToken string
+		token       string
+		wantCode    codes.Code
+		wantErr     string
+	}{
+		{"no auth", &fakeWorker{}, "", "", codes.OK, ""},
+		{"valid token", &fakeWorker{}, "s3cret", "s3cret", codes.OK, ""},
+		{"wrong token", &fakeWorker{}, "s3cret", "other", codes.Unauthenticated, ""},
+		{"missing token", &fakeWorker{}, "s3cret", "", codes.Unauthenticated, ""},
+		{"job mismatch", &fakeWorker{jobID: "3-bb"}, "", "", codes.OK, "worker returned job 3-bb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startFakeWorker(t, tt.worker, tt.workerToken)
+			r, err := NewRemoteProver([]string{addr}, 5*time.Second, 1, "", tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			res, err := r.dispatch(context.Background(), r.workers[0], req)
+			switch {
+			case tt.wantCode != codes.OK:
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("got %v, want code %s", err, tt.wantCode)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.JobID != req.JobID || res.Result.CurrentStateHash != "state-0xa" {
+					t.Fatalf("unexpected response %+v", res)
+				}
+			}
+		})
+	}
+}
+
+// workerResult returns the result a worker proving batch over curve with backend would send,
+// without the proof, which checkProofResult does not look at.
+func workerResult(t *testing.T, backend string, curve string, batch types.BatchStruct, batchNum int, previousHash string) ProofResult {
+	t.Helper()
+	id, err := curveID(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &LocalProver{backend: backend, curve: id}
+	w, stateHash, err := p.buildWitness(batch, batchNum, previousHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := w.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness, err := public.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	witnessVector, err := json.Marshal(public.Vector())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ProofResult{
+		Backend:           backend,
+		Curve:             curve,
+		WitnessVector:     witnessVector,
+		PublicWitness:     publicWitness,
+		PreviousStateHash: previousHash,
+		CurrentStateHash:  stateHash,
+	}
+}
+
+func TestCheckProofResult(t *testing.T) {
+	batch := testBatch()
+	previousHash := strings.Repeat("ab", 32)
+	otherHash := strings.Repeat("cd", 32)
+	valid := func() ProofResult {
+		return workerResult(t, common.ProverBackend, common.ProverCurve, batch, 7, previousHash)
+	}
+
+	otherBatch := testBatch()
+	otherBatch.Amounts[0] = "20"
+	otherBackend := BackendPlonk
+	if common.ProverBackend == BackendPlonk {
+		otherBackend = BackendGroth16
+	}
+
+	tests := []struct {
+		name    string
+		result  func() ProofResult
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"other backend", func() ProofResult {
+			r := valid()
+			r.Backend = otherBackend
+			return r
+		}, "worker proved with"},
+		{"other previous root", func() ProofResult {
+			r := valid()
+			r.PreviousStateHash = otherHash
+			return r
+		}, "worker proved from state root"},
+		{"other state root", func() ProofResult {
+			r := valid()
+			r.CurrentStateHash = otherHash
+			return r
+		}, "worker proved state root"},
+		{"witness of another batch number", func() ProofResult {
+			return workerResult(t, common.ProverBackend, common.ProverCurve, batch, 8, previousHash)
+		}, "public input 0"},
+		{"witness from another previous root", func() ProofResult {
+			r := workerResult(t, common.ProverBackend, common.ProverCurve, batch, 7, otherHash)
+			r.PreviousStateHash = previousHash
+			return r
+		}, "public input 1"},
+		{"witness of other transactions", func() ProofResult {
+			r := workerResult(t, common.ProverBackend, common.ProverCurve, otherBatch, 7, previousHash)
+			r.CurrentStateHash = BatchStateHash(batch)
+			return r
+		}, "does not match batch 7"},
+		{"witness vector of another witness", func() ProofResult {
+			r := valid()
+			r.WitnessVector = workerResult(t, common.ProverBackend, common.ProverCurve, batch, 8, previousHash).WitnessVector
+			return r
+		}, "witness vector from worker"},
+		{"undecodable witness", func() ProofResult {
+			r := valid()
+			r.PublicWitness = []byte{1, 2, 3}
+			return r
+		}, "error decoding public witness"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result()
+			err := checkProofResult(&result, batch, 7, previousHash)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package prover
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// SolidityCalldata is the proof of a batch encoded for the exported verifier contract.
+type SolidityCalldata struct {
+	BatchNumber  int      `json:"batch_number"`
+	Backend      string   `json:"backend"`
+	Proof        []string `json:"proof"`
+	PublicInputs []string `json:"public_inputs"`
+	Calldata     string   `json:"calldata"`
+}
+
+// ExportSolidityVerifier writes the Solidity verifier contract of the BN254 verification
+// key of backend to w.
+func ExportSolidityVerifier(backend string, w io.Writer) error {
+	vkBytes, err := os.ReadFile(VerificationKeyFile(backend, CurveBN254))
+	if err != nil {
+		return fmt.Errorf("error reading verification key: %w", err)
+	}
+
+	switch backend {
+	case BackendGroth16:
+		vk := groth16.NewVerifyingKey(ecc.BN254)
+		if err := json.Unmarshal(vkBytes, vk); err != nil {
+			return fmt.Errorf("error decoding verification key: %w", err)
+		}
+		if err := precomputeGroth16VerifyingKey(vk); err != nil {
+			return err
+		}
+		return vk.ExportSolidity(w)
+	case BackendPlonk:
+		vk := plonk.NewVerifyingKey(ecc.BN254)
+		if err := json.Unmarshal(vkBytes, vk); err != nil {
+			return fmt.Errorf("error decoding verification key: %w", err)
+		}
+		return vk.ExportSolidity(w)
+	default:
+		return fmt.Errorf("unknown prover backend %q", backend)
+	}
+}
+
+// BatchSolidityCalldata ABI encodes the stored proof and public witness of a BN254 batch as
+// a call to the exported verifier: verifyProof(uint256[8],uint256[n]) for Groth16 and
+// Verify(bytes,uint256[]) for PLONK.
+func BatchSolidityCalldata(batchNum int) (*SolidityCalldata, error) {
+	backend := ProofBackend(batchNum)
+	if curve := ProofCurve(batchNum); curve != CurveBN254 {
+		return nil, fmt.Errorf("batch %d was proved over %s, only %s proofs can be verified in Solidity", batchNum, curve, CurveBN254)
+	}
+
+	proofBytes, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting proof from db: %w", err)
+	}
+
+	publicWitnessBytes, err := air.GetPublicWitnessDbInstance().Get([]byte(fmt.Sprintf("public_witness_%d", batchNum)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting public witness from db: %w", err)
+	}
+
+	publicWitness, err := witness.New(ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	if err := publicWitness.UnmarshalBinary(publicWitnessBytes); err != nil {
+		return nil, fmt.Errorf("error decoding public witness: %w", err)
+	}
+	publicVector, ok := publicWitness.Vector().(fr_bn254.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected public witness type %T", publicWitness.Vector())
+	}
+
+	publicInputs := make([]*big.Int, len(publicVector))
+	for i := range publicVector {
+		publicInputs[i] = publicVector[i].BigInt(new(big.Int))
+	}
+
+	result := &SolidityCalldata{BatchNumber: batchNum, Backend: backend}
+	for _, input := range publicInputs {
+		result.PublicInputs = append(result.PublicInputs, input.String())
+	}
+
+	var calldata []byte
+	switch backend {
+	case BackendGroth16:
+		proof := groth16.NewProof(ecc.BN254)
+		if err := json.Unmarshal(proofBytes, proof); err != nil {
+			return nil, fmt.Errorf("error decoding proof: %w", err)
+		}
+
+		// the raw encoding of A, B and C is the EIP-197 layout expected by the verifier
+		var buf bytes.Buffer
+		if _, err := proof.WriteRawTo(&buf); err != nil {
+			return nil, err
+		}
+		rawProof := buf.Bytes()
+		if len(rawProof) < 8*32 {
+			return nil, fmt.Errorf("unexpected proof length %d", len(rawProof))
+		}
+		proofInts := make([]*big.Int, 8)
+		for i := range proofInts {
+			proofInts[i] = new(big.Int).SetBytes(rawProof[32*i : 32*(i+1)])
+			result.Proof = append(result.Proof, proofInts[i].String())
+		}
+
+		calldata, err = packCalldata(fmt.Sprintf("verifyProof(uint256[8],uint256[%d])", len(publicInputs)), "verifyProof", proofInts, publicInputs)
+		if err != nil {
+			return nil, err
+		}
+	case BackendPlonk:
+		proof := plonk.NewProof(ecc.BN254)
+		if err := json.Unmarshal(proofBytes, proof); err != nil {
+			return nil, fmt.Errorf("error decoding proof: %w", err)
+		}
+
+		solidityProof := proof.(*plonk_bn254.Proof).MarshalSolidity()
+		result.Proof = []string{hexutil.Encode(solidityProof)}
+
+		calldata, err = packCalldata("Verify(bytes,uint256[])", "Verify", solidityProof, publicInputs)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown prover backend %q", backend)
+	}
+
+	result.Calldata = hexutil.Encode(calldata)
+	return result, nil
+}
+
+// packCalldata ABI encodes a call to method with the given signature.
+func packCalldata(signature string, method string, args ...any) ([]byte, error) {
+	argTypes := strings.Split(strings.TrimSuffix(strings.TrimPrefix(signature, method+"("), ")"), ",")
+
+	var inputs []string
+	for i, argType := range argTypes {
+		inputs = append(inputs, fmt.Sprintf(`{"name":"arg%d","type":"%s"}`, i, argType))
+	}
+	abiJSON := fmt.Sprintf(`[{"type":"function","name":"%s","stateMutability":"view","inputs":[%s],"outputs":[]}]`, method, strings.Join(inputs, ","))
+
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.Pack(method, args...)
+}
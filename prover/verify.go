@@ -0,0 +1,192 @@
+package prover
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	air "github.com/airchains-network/evm-sequencer-node/airdb/air-leveldb"
+
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// VerifyBatchProof checks the stored proof of batchNum against its stored public witness
+// and the verification key of the backend and curve that produced it, so an invalid proof is caught
+// before it is posted to DA and the settlement layer.
+func VerifyBatchProof(batchNum int) error {
+	backend := ProofBackend(batchNum)
+	curve := ProofCurve(batchNum)
+	id, err := curveID(curve)
+	if err != nil {
+		return err
+	}
+
+	proofBytes, err := air.GetProofDbInstance().Get([]byte(fmt.Sprintf("proof_%d", batchNum)), nil)
+	if err != nil {
+		return fmt.Errorf("error getting proof from db: %w", err)
+	}
+
+	publicWitnessBytes, err := air.GetPublicWitnessDbInstance().Get([]byte(fmt.Sprintf("public_witness_%d", batchNum)), nil)
+	if err != nil {
+		return fmt.Errorf("error getting public witness from db: %w", err)
+	}
+
+	publicWitness, err := witness.New(id.ScalarField())
+	if err != nil {
+		return err
+	}
+	if err := publicWitness.UnmarshalBinary(publicWitnessBytes); err != nil {
+		return fmt.Errorf("error decoding public witness: %w", err)
+	}
+
+	vkBytes, err := os.ReadFile(VerificationKeyFile(backend, curve))
+	if err != nil {
+		return fmt.Errorf("error reading verification key: %w", err)
+	}
+
+	return VerifyProof(backend, curve, vkBytes, proofBytes, publicWitness)
+}
+
+// VerifyProof verifies a JSON encoded proof of backend over curve against a JSON encoded
+// verification key, as they are stored by the sequencer and sent to the settlement layer.
+func VerifyProof(backend string, curve string, vkBytes []byte, proofBytes []byte, publicWitness witness.Witness) error {
+	id, err := curveID(curve)
+	if err != nil {
+		return err
+	}
+
+	switch backend {
+	case BackendGroth16:
+		vk := groth16.NewVerifyingKey(id)
+		if err := json.Unmarshal(vkBytes, vk); err != nil {
+			return fmt.Errorf("error decoding verification key: %w", err)
+		}
+		if err := precomputeGroth16VerifyingKey(vk); err != nil {
+			return err
+		}
+		proof := groth16.NewProof(id)
+		if err := json.Unmarshal(proofBytes, proof); err != nil {
+			return fmt.Errorf("error decoding proof: %w", err)
+		}
+		return groth16.Verify(proof, vk, publicWitness)
+	case BackendPlonk:
+		vk := plonk.NewVerifyingKey(id)
+		if err := json.Unmarshal(vkBytes, vk); err != nil {
+			return fmt.Errorf("error decoding verification key: %w", err)
+		}
+		proof := plonk.NewProof(id)
+		if err := json.Unmarshal(proofBytes, proof); err != nil {
+			return fmt.Errorf("error decoding proof: %w", err)
+		}
+		return plonk.Verify(proof, vk, publicWitness)
+	default:
+		return fmt.Errorf("unknown prover backend %q", backend)
+	}
+}
+
+// WitnessFromVector builds the public witness over curve from the JSON witness vector
+// sent to the settlement layer. Every input of the batch circuit is public, so the vector
+// is the public witness.
+func WitnessFromVector(curve string, vectorJSON []byte) (witness.Witness, error) {
+	id, err := curveID(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := witnessVectorValues(vectorJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return fillWitness(id.ScalarField(), elements)
+}
+
+// CheckBatchStatement checks that the JSON witness vector of a batch proof states batch
+// batchNum going from previousStateRoot to stateRoot, so a proof verified against the
+// vector is bound to the pod and its roots.
+func CheckBatchStatement(vectorJSON []byte, batchNum int, previousStateRoot string, stateRoot string) error {
+	elements, err := witnessVectorValues(vectorJSON)
+	if err != nil {
+		return err
+	}
+
+	previousRoot, err := stateRootLimbs(previousStateRoot)
+	if err != nil {
+		return err
+	}
+	root, err := stateRootLimbs(stateRoot)
+	if err != nil {
+		return err
+	}
+	statement := []*big.Int{
+		big.NewInt(int64(batchNum)),
+		previousRoot[0].(*big.Int), previousRoot[1].(*big.Int),
+		root[0].(*big.Int), root[1].(*big.Int),
+	}
+
+	if len(elements) < len(statement) {
+		return fmt.Errorf("witness vector has %d inputs, expected at least %d", len(elements), len(statement))
+	}
+	for i, value := range statement {
+		if elements[i].Cmp(value) != 0 {
+			return fmt.Errorf("public input %d of the witness does not match batch %d from %s to %s", i, batchNum, previousStateRoot, stateRoot)
+		}
+	}
+	return nil
+}
+
+// witnessVectorValues decodes a JSON witness vector of decimal field elements.
+func witnessVectorValues(vectorJSON []byte) ([]*big.Int, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(vectorJSON, &elements); err != nil {
+		return nil, fmt.Errorf("error decoding witness vector: %w", err)
+	}
+
+	values := make([]*big.Int, 0, len(elements))
+	for _, element := range elements {
+		value, ok := new(big.Int).SetString(strings.Trim(string(element), `"`), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid witness element %s", element)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// fillWitness builds a public witness over field from its inputs.
+func fillWitness(field *big.Int, inputs []*big.Int) (witness.Witness, error) {
+	values := make(chan any, len(inputs))
+	for _, input := range inputs {
+		values <- input
+	}
+	close(values)
+
+	publicWitness, err := witness.New(field)
+	if err != nil {
+		return nil, err
+	}
+	if err := publicWitness.Fill(len(inputs), 0, values); err != nil {
+		return nil, err
+	}
+
+	return publicWitness, nil
+}
+
+// precomputeGroth16VerifyingKey computes the pairing of alpha and beta, which is not part of
+// the JSON encoding of the verification key.
+func precomputeGroth16VerifyingKey(vk groth16.VerifyingKey) error {
+	switch _vk := vk.(type) {
+	case *groth16_bls12381.VerifyingKey:
+		return _vk.Precompute()
+	case *groth16_bn254.VerifyingKey:
+		return _vk.Precompute()
+	default:
+		return fmt.Errorf("unsupported verifying key type %T", vk)
+	}
+}
@@ -0,0 +1,93 @@
+package prover
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// WorkerTokenEnv is the environment variable holding the token prover workers require from
+// the sequencer, on both sides.
+const WorkerTokenEnv = "PROVER_WORKER_TOKEN"
+
+// tokenCredentials sends the worker token with every call.
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+// workerDialOptions returns the transport and call credentials for connecting to prover
+// workers. Workers are verified against the CA in caFile when it is set, and reached over
+// plaintext otherwise.
+func workerDialOptions(caFile string, token string) ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading prover worker CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates in prover worker CA %s", caFile)
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: token, secure: caFile != ""}))
+	}
+	return opts, nil
+}
+
+// WorkerServerOptions returns the options of a prover worker gRPC server serving over TLS
+// with certFile and keyFile when they are set, and only answering calls carrying token
+// when it is set.
+func WorkerServerOptions(certFile string, keyFile string, token string) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if certFile != "" || keyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading prover worker TLS key pair: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if token != "" {
+		opts = append(opts, grpc.UnaryInterceptor(tokenInterceptor(token)))
+	}
+	return opts, nil
+}
+
+// tokenInterceptor rejects calls that do not carry token as a bearer token.
+func tokenInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var presented string
+		if values := md.Get("authorization"); len(values) > 0 {
+			presented, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid prover worker token")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// batchSpans holds the span of every batch in the pipeline. A batch is handled by a
+// different worker at each stage, so its span outlives the calls that start and end it.
+var batchSpans = struct {
+	sync.Mutex
+	spans map[int]trace.Span
+}{spans: map[int]trace.Span{}}
+
+// BatchContext returns ctx carrying the span of batchNumber, which the spans of its stages
+// are children of. The span is started by the first stage that handles the batch, which
+// after a restart may not be collection.
+func BatchContext(ctx context.Context, batchNumber int) context.Context {
+	batchSpans.Lock()
+	defer batchSpans.Unlock()
+
+	span, ok := batchSpans.spans[batchNumber]
+	if !ok {
+		_, span = Tracer.Start(context.Background(), "batch",
+			trace.WithNewRoot(),
+			trace.WithAttributes(attribute.Int("batch.number", batchNumber)),
+		)
+		batchSpans.spans[batchNumber] = span
+	}
+	return trace.ContextWithSpan(ctx, span)
+}
+
+// EndBatch ends the span of batchNumber, marking it failed with err when err is not nil.
+func EndBatch(batchNumber int, err error) {
+	batchSpans.Lock()
+	span, ok := batchSpans.spans[batchNumber]
+	delete(batchSpans.spans, batchNumber)
+	batchSpans.Unlock()
+
+	if ok {
+		End(span, err)
+	}
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+	ExporterNone = "none"
+)
+
+// Tracer creates the spans of the sequencer. Until Init is called its spans are not
+// recorded.
+var Tracer = otel.Tracer("github.com/airchains-network/evm-sequencer-node")
+
+var provider *sdktrace.TracerProvider
+
+// Config selects where the spans are exported.
+type Config struct {
+	// ServiceName is reported as service.name.
+	ServiceName string
+	// Exporter is "otlp", "file" or "none".
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector. When empty the
+	// OTEL_EXPORTER_OTLP_ENDPOINT variable, or localhost:4318, is used.
+	Endpoint string
+	// Insecure sends the spans to the collector over plain HTTP.
+	Insecure bool
+	// File receives the spans as JSON, one per line, with the "file" exporter.
+	File string
+	// SampleRatio is the fraction of traces that are recorded.
+	SampleRatio float64
+}
+
+// Init sets the global tracer provider to export the spans as configured.
+func Init(ctx context.Context, config Config) error {
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case ExporterNone, "":
+		return nil
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		otlpExporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return fmt.Errorf("error creating OTLP exporter: %w", err)
+		}
+		exporter = otlpExporter
+	case ExporterFile:
+		if err := os.MkdirAll(filepath.Dir(config.File), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		fileExporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			return fmt.Errorf("error creating file exporter: %w", err)
+		}
+		exporter = fileExporter
+	default:
+		return fmt.Errorf("invalid trace exporter %q", config.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", config.ServiceName),
+	))
+	if err != nil {
+		return err
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return nil
+}
+
+// Flush exports the spans that have ended. It is called before the sequencer exits on a
+// failed batch, so the trace of the failure is not lost.
+func Flush(ctx context.Context) {
+	if provider != nil {
+		_ = provider.ForceFlush(ctx)
+	}
+}
+
+// Shutdown exports the remaining spans and stops the exporter.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start starts a span that is a child of the span in ctx. Without a span in ctx nothing
+// is recorded, so calls made outside a batch, such as health checks, add no traces.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return Tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends span, marking it failed with err when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the headers that carry the trace of ctx to another process.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx continuing the trace carried by headers, as returned by Inject.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
@@ -1,5 +1,7 @@
 package types
 
+import "encoding/json"
+
 type ProofStruct struct {
 	Ar struct {
 		X string `json:"X"`
@@ -27,14 +29,21 @@ type ProofStruct struct {
 }
 
 type DAUploadStruct struct {
-	Proof             ProofStruct `json:"proof"`
-	TxnHashes         []string    `json:"txnHashes"`
-	CurrentStateHash  string      `json:"currentStateHash"`
-	PreviousStateHash string      `json:"previousStateHash"`
+	Version           int               `json:"version"`
+	Blob              []byte            `json:"blob,omitempty"`
+	Manifest          *DAManifestStruct `json:"manifest,omitempty"`
+	Proof             json.RawMessage   `json:"proof"`
+	ProofBackend      string            `json:"proofBackend"`
+	ProofCurve        string            `json:"proofCurve"`
+	TxnHashes         []string          `json:"txnHashes"`
+	CurrentStateHash  string            `json:"currentStateHash"`
+	PreviousStateHash string            `json:"previousStateHash"`
 	MetaData          struct {
 		ChainID     string `json:"chainID"`
 		BatchNumber int    `json:"batchNumber"`
 	} `json:"metaData"`
+	PubKey    []byte `json:"pub_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
 }
 
 type DAResponseStruct struct {
@@ -43,3 +52,31 @@ type DAResponseStruct struct {
 	Message   string `json:"message"`
 	DaKeyHash string `json:"daKeyHash"`
 }
+
+// DABatchBlob is everything needed to rebuild a batch from DA: the canonical (RLP or typed
+// envelope) encoding of its transactions and the RLP encoded headers of the blocks they
+// were included in, in order.
+type DABatchBlob struct {
+	ChainID           string
+	BatchNumber       uint64
+	PreviousStateHash string
+	CurrentStateHash  string
+	Headers           [][]byte
+	Transactions      [][]byte
+}
+
+// DAManifestStruct lists the chunks of a batch blob that was too large for a single DA
+// submission. The blob is the concatenation of the chunks in order.
+type DAManifestStruct struct {
+	Size   int      `json:"size"`
+	Hash   string   `json:"hash"`
+	Chunks []string `json:"chunks"`
+}
+
+// DAChunkStruct is one chunk of a batch blob, submitted before its manifest.
+type DAChunkStruct struct {
+	Version     int    `json:"version"`
+	BatchNumber int    `json:"batchNumber"`
+	Index       int    `json:"index"`
+	Data        []byte `json:"data"`
+}
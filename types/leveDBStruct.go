@@ -60,10 +60,54 @@ type DAStruct struct {
 	BatchNumber       string `json:"batch_number"`
 	PreviousStateHash string `json:"previous_state_hash"`
 	CurrentStateHash  string `json:"current_state_hash"`
+	// DAConfirmed is set once the blob has been read back from the DA layer and matched
+	// against the stored batch and proof.
+	DAConfirmed bool `json:"da_confirmed"`
+	// Codec, sizes and number of DA submissions of the batch blob. The ratio is the
+	// uncompressed size over the compressed size.
+	Codec            string  `json:"codec,omitempty"`
+	BlobSize         int     `json:"blob_size,omitempty"`
+	CompressedSize   int     `json:"compressed_size,omitempty"`
+	CompressionRatio float64 `json:"compression_ratio,omitempty"`
+	Chunks           int     `json:"chunks,omitempty"`
+	// BlobHash is the hex sha256 of the encoded batch blob.
+	BlobHash string `json:"blob_hash,omitempty"`
 }
 
 // SettlementLayerChainInfoStruct ChainInfoStruct is the struct for chainInfo.json file
 type SettlementLayerChainInfoStruct struct {
 	ChainId   string `json:"chain_id"`
 	ChainName string `json:"chain_name"`
+	// CircuitVersion is the version of the batch circuit whose verification key was
+	// registered. Stations registered before it was recorded have version 1.
+	CircuitVersion int `json:"circuit_version,omitempty"`
+}
+
+// BatchFailureStruct records why a batch could not be submitted, stored in the batches
+// db under "batch-failure-<batch number>".
+type BatchFailureStruct struct {
+	BatchNumber int    `json:"batch_number"`
+	Stage       string `json:"stage"`
+	Error       string `json:"error"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// BatchStateStruct is the lifecycle stage of a batch, stored in the batches db under
+// "batch-state-<batch number>". The batch holds the transactions TxStart+1 to TxEnd of the
+// txns db. When Stage is "failed", FailedStage and Error say what failed and ResumeStage is
+// the last stage the batch completed, from which it is resumed.
+type BatchStateStruct struct {
+	BatchNumber      int    `json:"batch_number"`
+	Stage            string `json:"stage"`
+	TxStart          int    `json:"tx_start"`
+	TxEnd            int    `json:"tx_end"`
+	SealReason       string `json:"seal_reason,omitempty"`
+	CurrentStateHash string `json:"current_state_hash,omitempty"`
+	DAKey            string `json:"da_key,omitempty"`
+	// SettlementResponse is what the settlement layer returned when the pod was added.
+	SettlementResponse string `json:"settlement_response,omitempty"`
+	FailedStage        string `json:"failed_stage,omitempty"`
+	ResumeStage        string `json:"resume_stage,omitempty"`
+	Error              string `json:"error,omitempty"`
+	UpdatedAt          int64  `json:"updated_at"`
 }
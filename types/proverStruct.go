@@ -0,0 +1,13 @@
+package types
+
+// ProofJobStruct is the record of a proving job dispatched to a remote prover worker,
+// stored in the proof db under "proof_job_<job id>".
+type ProofJobStruct struct {
+	JobID       string `json:"job_id"`
+	BatchNumber int    `json:"batch_number"`
+	Worker      string `json:"worker"`
+	Attempt     int    `json:"attempt"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
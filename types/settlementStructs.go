@@ -8,6 +8,7 @@ type ChainInfoStruct struct {
 	} `json:"chainInfo"`
 	DaInfo struct {
 		DaSelected      string `json:"daSelected"`
+		DaSecondary     string `json:"daSecondary"` // DA layer used after DAFailoverAfter failures of daSelected, optional
 		DaWalletAddress string `json:"daWalletAddress"`
 		DaWalletKeypair string `json:"daWalletKeypair"`
 	} `json:"daInfo"`
@@ -16,6 +17,14 @@ type ChainInfoStruct struct {
 	} `json:"sequencerInfo"`
 }
 
+// LatestPodStruct is the latest pod the settlement layer holds for a station, sent JSON
+// encoded in the data of the /latest-pod response. PodNumber is 0 when no pod was added.
+type LatestPodStruct struct {
+	PodNumber      uint64 `json:"pod_number"`
+	MerkleRootHash string `json:"merkle_root_hash"`
+	Verified       bool   `json:"verified"`
+}
+
 type SettlementClientResponseStruct struct {
 	Status      bool   `json:"status"`
 	Data        string `json:"data"`